@@ -0,0 +1,407 @@
+// Code generated by resoursea-gen. DO NOT EDIT.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Router generated for the api.API Resource tree
+type APIRouter struct {
+	// Initial state of each Resource
+	r0 *API
+	r1 *Gophers
+	r2 *Gopher
+	r3 *Version
+	r4 *Maltese
+	r5 *Date
+	r6 *time.Time
+}
+
+// Creates the Router, initializing the Resource tree
+func NewAPIRouter(object API) (*APIRouter, error) {
+	h := &APIRouter{}
+	v0 := new(API)
+	*v0 = object
+	h.r0 = v0
+	v1 := new(Gophers)
+	*v1 = v0.Gophers
+	h.r1 = v1
+	{
+		o0, o1 := h.r1.Init()
+		h.r1 = new(Gophers)
+		*h.r1 = o0
+		if o1 != nil {
+			return nil, o1
+		}
+	}
+	v2 := new(Gopher)
+	if len(*v1) > 0 {
+		*v2 = (*v1)[0]
+	}
+	h.r2 = v2
+	v3 := new(Version)
+	*v3 = v0.Version
+	h.r3 = v3
+	{
+		o0, o1 := h.r3.Init()
+		h.r3 = new(Version)
+		if o0 != nil {
+			*h.r3 = *o0
+		}
+		if o1 != nil {
+			return nil, o1
+		}
+	}
+	v4 := new(Maltese)
+	*v4 = v0.Maltese
+	h.r4 = v4
+	v5 := new(Date)
+	*v5 = v0.Date
+	h.r5 = v5
+	v6 := new(time.Time)
+	*v6 = v5.Time
+	h.r6 = v6
+	return h, nil
+}
+
+func (h *APIRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	uri := strings.Split(strings.Split(req.URL.RequestURI(), "?")[0], "/")[1:]
+	if uri[0] != "api" {
+		WriteError(w, errors.New("Route "+"api"+" not match with "+uri[0]), http.StatusNotFound)
+		return
+	}
+	ids := [1]ID{NilID}
+	m, err := h.route0(uri[1:], strings.ToLower(req.Method), &ids)
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	switch m {
+	case 0:
+		h.method0(w, req, &ids)
+	case 1:
+		h.method1(w, req, &ids)
+	case 2:
+		h.method2(w, req, &ids)
+	case 3:
+		h.method3(w, req, &ids)
+	case 4:
+		h.method4(w, req, &ids)
+	case 5:
+		h.method5(w, req, &ids)
+	}
+}
+
+func (h *APIRouter) route0(uri []string, httpMethod string, ids *[1]ID) (int, error) {
+	if len(uri) == 0 {
+		switch httpMethod {
+		case "getdogbark":
+			return 0, nil
+		}
+		return -1, errors.New("Method " + httpMethod + " not found in the " + "[api] *api.API")
+	}
+	if len(uri) == 1 {
+		switch httpMethod + uri[0] {
+		case "getdogbark":
+			return 0, nil
+		}
+	}
+	switch uri[0] {
+	case "date":
+		return h.route1(uri[1:], httpMethod, ids)
+	case "gophers":
+		return h.route2(uri[1:], httpMethod, ids)
+	case "version":
+		return h.route4(uri[1:], httpMethod, ids)
+	}
+	return -1, errors.New("Not exist any Child '" + uri[0] + "' or Action '" + httpMethod + strings.Title(uri[0]) + "' in the " + "[api] *api.API")
+}
+
+func (h *APIRouter) route1(uri []string, httpMethod string, ids *[1]ID) (int, error) {
+	if len(uri) == 0 {
+		switch httpMethod {
+		case "get":
+			return 1, nil
+		}
+		return -1, errors.New("Method " + httpMethod + " not found in the " + "[date] *api.Date")
+	}
+	if len(uri) == 1 {
+		switch httpMethod + uri[0] {
+		case "get":
+			return 1, nil
+		}
+	}
+	return -1, errors.New("Not exist any Child '" + uri[0] + "' or Action '" + httpMethod + strings.Title(uri[0]) + "' in the " + "[date] *api.Date")
+}
+
+func (h *APIRouter) route2(uri []string, httpMethod string, ids *[1]ID) (int, error) {
+	if len(uri) == 0 {
+		switch httpMethod {
+		case "get":
+			return 2, nil
+		}
+		return -1, errors.New("Method " + httpMethod + " not found in the " + "[gophers] *api.Gophers")
+	}
+	if len(uri) == 1 {
+		switch httpMethod + uri[0] {
+		case "get":
+			return 2, nil
+		}
+	}
+	ids[0] = NewID(uri[0])
+	return h.route3(uri[1:], httpMethod, ids)
+}
+
+func (h *APIRouter) route3(uri []string, httpMethod string, ids *[1]ID) (int, error) {
+	if len(uri) == 0 {
+		switch httpMethod {
+		case "get":
+			return 3, nil
+		case "getmessage":
+			return 4, nil
+		}
+		return -1, errors.New("Method " + httpMethod + " not found in the " + "[gophers] *api.Gopher")
+	}
+	if len(uri) == 1 {
+		switch httpMethod + uri[0] {
+		case "get":
+			return 3, nil
+		case "getmessage":
+			return 4, nil
+		}
+	}
+	return -1, errors.New("Not exist any Child '" + uri[0] + "' or Action '" + httpMethod + strings.Title(uri[0]) + "' in the " + "[gophers] *api.Gopher")
+}
+
+func (h *APIRouter) route4(uri []string, httpMethod string, ids *[1]ID) (int, error) {
+	if len(uri) == 0 {
+		switch httpMethod {
+		case "get":
+			return 5, nil
+		}
+		return -1, errors.New("Method " + httpMethod + " not found in the " + "[version] *api.Version")
+	}
+	if len(uri) == 1 {
+		switch httpMethod + uri[0] {
+		case "get":
+			return 5, nil
+		}
+	}
+	return -1, errors.New("Not exist any Child '" + uri[0] + "' or Action '" + httpMethod + strings.Title(uri[0]) + "' in the " + "[version] *api.Version")
+}
+
+// [GETDogBark] func(*api.API, api.Doger) string
+func (h *APIRouter) method0(w http.ResponseWriter, req *http.Request, ids *[1]ID) {
+	req = WithRoute(req, &RouteInfo{
+		Template:   "/api/dogbark",
+		HTTPMethod: req.Method,
+		Method:     "GETDogBark",
+		Resource:   "*api.API",
+	})
+	ctx := req.Context()
+	_ = ctx
+	errs := []error{}
+	_ = errs
+	var s2 *API
+	var s3 *Maltese
+	s2 = new(API)
+	*s2 = *h.r0
+	s3 = new(Maltese)
+	*s3 = *h.r4
+	teardown := func(outcome error) {
+		_ = outcome
+	}
+	if err := ctx.Err(); err != nil {
+		WriteError(w, err, ErrorStatus(err))
+		teardown(err)
+		return
+	}
+	o0 := s2.GETDogBark(s3)
+	teardown(WriteOutput(w, []string{"string"}, &o0))
+}
+
+// [GET] func(*api.Date) *api.Date
+func (h *APIRouter) method1(w http.ResponseWriter, req *http.Request, ids *[1]ID) {
+	req = WithRoute(req, &RouteInfo{
+		Template:   "/api/date",
+		HTTPMethod: req.Method,
+		Method:     "GET",
+		Resource:   "*api.Date",
+	})
+	ctx := req.Context()
+	_ = ctx
+	errs := []error{}
+	_ = errs
+	var s2 *Date
+	s2 = new(Date)
+	*s2 = *h.r5
+	if ctx.Err() == nil {
+		o0 := s2.New()
+		s2 = o0
+	}
+	teardown := func(outcome error) {
+		_ = outcome
+	}
+	if err := ctx.Err(); err != nil {
+		WriteError(w, err, ErrorStatus(err))
+		teardown(err)
+		return
+	}
+	o0 := s2.GET()
+	teardown(WriteOutput(w, []string{"Date"}, &o0))
+}
+
+// [GET] func(*api.Gophers, error) (*api.Gophers, error)
+func (h *APIRouter) method2(w http.ResponseWriter, req *http.Request, ids *[1]ID) {
+	req = WithRoute(req, &RouteInfo{
+		Template:   "/api/gophers",
+		HTTPMethod: req.Method,
+		Method:     "GET",
+		Resource:   "*api.Gophers",
+	})
+	ctx := req.Context()
+	_ = ctx
+	errs := []error{}
+	_ = errs
+	var s2 *Gophers
+	s2 = new(Gophers)
+	*s2 = *h.r1
+	teardown := func(outcome error) {
+		_ = outcome
+	}
+	if err := ctx.Err(); err != nil {
+		WriteError(w, err, ErrorStatus(err))
+		teardown(err)
+		return
+	}
+	var a1 error
+	if len(errs) > 0 {
+		a1 = errs[0]
+	}
+	o0, o1 := s2.GET(a1)
+	teardown(WriteOutput(w, []string{"Gophers", "error"}, &o0, &o1))
+}
+
+// [GET] func(*api.Gopher, error) (*api.Gopher, error)
+func (h *APIRouter) method3(w http.ResponseWriter, req *http.Request, ids *[1]ID) {
+	req = WithRoute(req, &RouteInfo{
+		Template:   "/api/gophers/:gopher",
+		HTTPMethod: req.Method,
+		Method:     "GET",
+		Resource:   "*api.Gopher",
+	})
+	ctx := req.Context()
+	_ = ctx
+	errs := []error{}
+	_ = errs
+	var s2 *Gophers
+	var s3 *Gopher
+	s2 = new(Gophers)
+	*s2 = *h.r1
+	s3 = new(Gopher)
+	*s3 = *h.r2
+	if ctx.Err() == nil {
+		var a0 Gophers
+		if s2 != nil {
+			a0 = *s2
+		}
+		o0, o1 := s3.New(a0, ids[0])
+		s3 = o0
+		if o1 != nil {
+			var e error = &DependencyError{Type: reflect.TypeOf((*Gopher)(nil)), Err: o1}
+			errs = append(errs, e)
+		}
+	}
+	teardown := func(outcome error) {
+		_ = outcome
+	}
+	if err := ctx.Err(); err != nil {
+		WriteError(w, err, ErrorStatus(err))
+		teardown(err)
+		return
+	}
+	var a3 error
+	if len(errs) > 0 {
+		a3 = errs[0]
+	}
+	o0, o1 := s3.GET(a3)
+	teardown(WriteOutput(w, []string{"Gopher", "error"}, &o0, &o1))
+}
+
+// [GETMessage] func(*api.Gopher, error) (string, error)
+func (h *APIRouter) method4(w http.ResponseWriter, req *http.Request, ids *[1]ID) {
+	req = WithRoute(req, &RouteInfo{
+		Template:   "/api/gophers/:gopher/message",
+		HTTPMethod: req.Method,
+		Method:     "GETMessage",
+		Resource:   "*api.Gopher",
+	})
+	ctx := req.Context()
+	_ = ctx
+	errs := []error{}
+	_ = errs
+	var s2 *Gophers
+	var s3 *Gopher
+	s2 = new(Gophers)
+	*s2 = *h.r1
+	s3 = new(Gopher)
+	*s3 = *h.r2
+	if ctx.Err() == nil {
+		var a0 Gophers
+		if s2 != nil {
+			a0 = *s2
+		}
+		o0, o1 := s3.New(a0, ids[0])
+		s3 = o0
+		if o1 != nil {
+			var e error = &DependencyError{Type: reflect.TypeOf((*Gopher)(nil)), Err: o1}
+			errs = append(errs, e)
+		}
+	}
+	teardown := func(outcome error) {
+		_ = outcome
+	}
+	if err := ctx.Err(); err != nil {
+		WriteError(w, err, ErrorStatus(err))
+		teardown(err)
+		return
+	}
+	var a3 error
+	if len(errs) > 0 {
+		a3 = errs[0]
+	}
+	o0, o1 := s3.GETMessage(a3)
+	teardown(WriteOutput(w, []string{"string", "error"}, &o0, &o1))
+}
+
+// [GET] func(*api.Version) *api.Version
+func (h *APIRouter) method5(w http.ResponseWriter, req *http.Request, ids *[1]ID) {
+	req = WithRoute(req, &RouteInfo{
+		Template:   "/api/version",
+		HTTPMethod: req.Method,
+		Method:     "GET",
+		Resource:   "*api.Version",
+	})
+	ctx := req.Context()
+	_ = ctx
+	errs := []error{}
+	_ = errs
+	var s2 *Version
+	s2 = new(Version)
+	*s2 = *h.r3
+	teardown := func(outcome error) {
+		_ = outcome
+	}
+	if err := ctx.Err(); err != nil {
+		WriteError(w, err, ErrorStatus(err))
+		teardown(err)
+		return
+	}
+	o0 := s2.GET()
+	teardown(WriteOutput(w, []string{"Version"}, &o0))
+}
@@ -5,7 +5,9 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -169,6 +171,27 @@ type GophersResp struct {
 	Gophers Gophers
 }
 
+// Send the request to the Router and return the recorded response
+// The headers are pairs of name and value, like "Token", "abc"
+func doRequest(rt Router, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rt.ServeHTTP(w, req)
+	return w
+}
+
+// Decode the JSON response in v
+// If it isn't a valid JSON, it fails the Test
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	err := json.Unmarshal(w.Body.Bytes(), v)
+	if err != nil {
+		t.Fatalf("Invalid response %q: %s", w.Body.String(), err)
+	}
+}
+
 // Try to get any Error from the response
 // If it returned an error, it fails the Test
 func errorTest(w *httptest.ResponseRecorder, t *testing.T) {
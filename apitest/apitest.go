@@ -0,0 +1,291 @@
+// Package apitest tests the Resource trees in process, without starting a server.
+//
+// It creates the Router for a root Resource, optionally replacing some of the
+// dependencies with test doubles, and sends requests with fluent assertions:
+//
+//	at := apitest.New(t, API{}, apitest.Override((*Mailer)(nil), &FakeMailer{}))
+//	at.POST("/api/users").JSON(user).Expect().
+//		Status(http.StatusOK).
+//		Output("User", &created)
+//
+// The outputs of the mapped methods are found in the response by their names,
+// the same names the Router uses to encode them.
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/resoursea/api"
+)
+
+// Sends the requests to the Router created for the tested Resource tree
+type Tester struct {
+	t testing.TB
+
+	// The Router under test, it can be configured before sending the requests
+	Router api.Router
+}
+
+// Changes how the Router is created
+type Option func(o *options)
+
+type options struct {
+	config api.Config
+	args   []string
+}
+
+// Replace the dependencies of the target type by the double
+// The target is a nil pointer to the interface or struct type, like (*Mailer)(nil)
+// The same double is injected in all requests, so it can record the calls
+func Override(target interface{}, double interface{}) Option {
+	return func(o *options) {
+		if o.config.Overrides == nil {
+			o.config.Overrides = map[reflect.Type]interface{}{}
+		}
+		o.config.Overrides[reflect.TypeOf(target).Elem()] = double
+	}
+}
+
+// Use these environment variables instead of the process ones
+func Env(env map[string]string) Option {
+	return func(o *options) {
+		o.config.LookupEnv = func(key string) (string, bool) {
+			value, exist := env[key]
+			return value, exist
+		}
+	}
+}
+
+// Read these config files when creating the Router
+func ConfigFiles(files ...string) Option {
+	return func(o *options) {
+		o.config.Files = append(o.config.Files, files...)
+	}
+}
+
+// Define the name and the tag of the root Resource, like NewRouter optional arguments
+func Root(args ...string) Option {
+	return func(o *options) {
+		o.args = args
+	}
+}
+
+// Creates the Router for the object, failing the test if it can't be created
+func New(t testing.TB, object interface{}, opts ...Option) *Tester {
+	t.Helper()
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	rt, err := api.NewRouterWithConfig(object, &o.config, o.args...)
+	if err != nil {
+		t.Fatalf("Error creating the Router: %s", err)
+	}
+
+	return &Tester{t: t, Router: rt}
+}
+
+// A request being built, sent when Expect is called
+type Request struct {
+	t      testing.TB
+	router http.Handler
+	req    *http.Request
+	body   []byte
+}
+
+// Start a request with this HTTP method and URI
+func (at *Tester) Request(method, uri string) *Request {
+	at.t.Helper()
+
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		at.t.Fatalf("Invalid request %s %s: %s", method, uri, err)
+	}
+	return &Request{t: at.t, router: at.Router, req: req}
+}
+
+func (at *Tester) GET(uri string) *Request {
+	return at.Request("GET", uri)
+}
+
+func (at *Tester) POST(uri string) *Request {
+	return at.Request("POST", uri)
+}
+
+func (at *Tester) PUT(uri string) *Request {
+	return at.Request("PUT", uri)
+}
+
+func (at *Tester) PATCH(uri string) *Request {
+	return at.Request("PATCH", uri)
+}
+
+func (at *Tester) DELETE(uri string) *Request {
+	return at.Request("DELETE", uri)
+}
+
+// Set a header of the request
+func (r *Request) Header(key, value string) *Request {
+	r.req.Header.Set(key, value)
+	return r
+}
+
+// Send this raw body
+func (r *Request) Body(body []byte) *Request {
+	r.body = body
+	return r
+}
+
+// Send the value encoded as JSON
+func (r *Request) JSON(v interface{}) *Request {
+	r.t.Helper()
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("Error encoding the request body: %s", err)
+	}
+	r.req.Header.Set("Content-Type", "application/json")
+	return r.Body(body)
+}
+
+// Send the request and return the response to be asserted
+func (r *Request) Expect() *Response {
+	if r.body != nil {
+		r.req.Body = io.NopCloser(bytes.NewReader(r.body))
+		r.req.ContentLength = int64(len(r.body))
+	}
+
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, r.req)
+
+	return &Response{t: r.t, req: r.req, Recorder: w}
+}
+
+// The response received, with the assertions over it
+// A failed assertion marks the test as failed, but the test continues
+type Response struct {
+	t   testing.TB
+	req *http.Request
+
+	// The recorded response, for the assertions not provided here
+	Recorder *httptest.ResponseRecorder
+
+	// The response body decoded by its output names
+	outputs map[string]json.RawMessage
+}
+
+// Assert the status code
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+
+	if r.Recorder.Code != code {
+		r.t.Errorf("%s %s: expected the status %d, got %d: %s",
+			r.req.Method, r.req.URL, code, r.Recorder.Code, r.Recorder.Body)
+	}
+	return r
+}
+
+// Assert the value of a header
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+
+	if got := r.Recorder.Header().Get(key); got != value {
+		r.t.Errorf("%s %s: expected the header %s to be %q, got %q",
+			r.req.Method, r.req.URL, key, value, got)
+	}
+	return r
+}
+
+// Decode the output with this name into v
+// The outputs are named by their types, like Gopher, and errors by error or errors
+func (r *Response) Output(name string, v interface{}) *Response {
+	r.t.Helper()
+
+	raw, ok := r.output(name)
+	if !ok {
+		return r
+	}
+
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		r.t.Errorf("%s %s: error decoding the output %s: %s", r.req.Method, r.req.URL, name, err)
+	}
+	return r
+}
+
+// Assert the output with this name is equal to the expected value
+// Both are compared as JSON, so the expected value can be a struct or a map
+func (r *Response) Equal(name string, expected interface{}) *Response {
+	r.t.Helper()
+
+	raw, ok := r.output(name)
+	if !ok {
+		return r
+	}
+
+	encoded, err := json.Marshal(expected)
+	if err != nil {
+		r.t.Fatalf("Error encoding the expected %s: %s", name, err)
+	}
+
+	var got, want interface{}
+	json.Unmarshal(raw, &got)
+	json.Unmarshal(encoded, &want)
+
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("%s %s: expected the output %s to be %s, got %s",
+			r.req.Method, r.req.URL, name, encoded, compact(raw))
+	}
+	return r
+}
+
+// Assert the error message answered contains the text
+func (r *Response) Error(text string) *Response {
+	r.t.Helper()
+
+	var message string
+	r.Output("error", &message)
+	if !strings.Contains(message, text) {
+		r.t.Errorf("%s %s: expected an error containing %q, got %q",
+			r.req.Method, r.req.URL, text, message)
+	}
+	return r
+}
+
+// Return the raw output with this name, failing the test if it doesn't exist
+func (r *Response) output(name string) (json.RawMessage, bool) {
+	r.t.Helper()
+
+	if r.outputs == nil {
+		err := json.Unmarshal(r.Recorder.Body.Bytes(), &r.outputs)
+		if err != nil {
+			r.t.Errorf("%s %s: the response isn't a JSON object: %s", r.req.Method, r.req.URL, r.Recorder.Body)
+			return nil, false
+		}
+	}
+
+	raw, exist := r.outputs[name]
+	if !exist {
+		r.t.Errorf("%s %s: the output %s wasn't found in the response: %s",
+			r.req.Method, r.req.URL, name, r.Recorder.Body)
+		return nil, false
+	}
+	return raw, true
+}
+
+func compact(raw json.RawMessage) string {
+	var b bytes.Buffer
+	if json.Compact(&b, raw) != nil {
+		return string(raw)
+	}
+	return b.String()
+}
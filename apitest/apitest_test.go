@@ -0,0 +1,130 @@
+// This package tests the fluent requests and the dependency overrides
+package apitest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type Notifier interface {
+	Notify(msg string) error
+}
+
+type SMTP struct{}
+
+func (s *SMTP) Notify(msg string) error {
+	return errors.New("no network in the tests")
+}
+
+type Clock struct {
+	Now string
+}
+
+func (c *Clock) New() *Clock {
+	return &Clock{Now: "real time"}
+}
+
+type Shop struct {
+	Orders Orders
+	SMTP   SMTP
+}
+
+type Orders struct{}
+
+type Order struct {
+	Item string
+	At   string
+}
+
+func (o *Orders) POST(req *http.Request, n Notifier, c Clock) (*Order, error) {
+	order := &Order{}
+	err := json.NewDecoder(req.Body).Decode(order)
+	if err != nil {
+		return nil, err
+	}
+	order.At = c.Now
+	return order, n.Notify("ordered " + order.Item)
+}
+
+type FakeNotifier struct {
+	Sent []string
+}
+
+func (f *FakeNotifier) Notify(msg string) error {
+	f.Sent = append(f.Sent, msg)
+	return nil
+}
+
+func TestOverrides(t *testing.T) {
+	fake := &FakeNotifier{}
+	at := New(t, Shop{},
+		Override((*Notifier)(nil), fake),
+		Override((*Clock)(nil), Clock{Now: "noon"}),
+	)
+
+	var order Order
+	at.POST("/shop/orders").JSON(Order{Item: "book"}).Expect().
+		Status(http.StatusOK).
+		Header("Content-Type", "application/json").
+		Output("Order", &order).
+		Equal("Order", map[string]string{"Item": "book", "At": "noon"})
+
+	if order.Item != "book" {
+		t.Errorf("Expected the book order, got %+v", order)
+	}
+
+	// The same double is used by all requests
+	at.POST("/shop/orders").JSON(Order{Item: "pen"}).Expect().Status(http.StatusOK)
+	if fmt.Sprint(fake.Sent) != "[ordered book ordered pen]" {
+		t.Errorf("Expected two notifications, got %q", fake.Sent)
+	}
+}
+
+func TestWithoutOverrides(t *testing.T) {
+	at := New(t, Shop{})
+	at.POST("/shop/orders").JSON(Order{Item: "book"}).Expect().
+		Error("no network").
+		Equal("Order", Order{Item: "book", At: "real time"})
+}
+
+// Records the failures instead of failing the test
+type spy struct {
+	testing.TB
+	failures []string
+}
+
+func (s *spy) Helper() {}
+
+func (s *spy) Errorf(format string, args ...interface{}) {
+	s.failures = append(s.failures, fmt.Sprintf(format, args...))
+}
+
+func (s *spy) Fatalf(format string, args ...interface{}) {
+	s.Errorf(format, args...)
+}
+
+func TestFailedAssertions(t *testing.T) {
+	s := &spy{TB: t}
+	at := New(s, Shop{}, Override((*Notifier)(nil), &FakeNotifier{}))
+
+	at.GET("/shop/orders").Expect().Status(http.StatusOK)
+	at.POST("/shop/orders").JSON(Order{Item: "book"}).Expect().
+		Equal("Order", Order{Item: "pen"}).
+		Output("Missing", nil)
+
+	if len(s.failures) != 3 {
+		t.Errorf("Expected 3 failures, got %q", s.failures)
+	}
+}
+
+func TestInvalidOverride(t *testing.T) {
+	s := &spy{TB: t}
+	New(s, Shop{}, Override((*Notifier)(nil), &Clock{}))
+
+	if len(s.failures) != 1 {
+		t.Errorf("Expected the Router creation to fail, got %q", s.failures)
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	gocontext "context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -26,12 +28,17 @@ var (
 	errorSliceType        = reflect.TypeOf(([]error)(nil))
 	errorType             = errorSliceType.Elem()
 	errorNilValue         = reflect.New(errorType).Elem()
+	contextType           = reflect.TypeOf((*gocontext.Context)(nil)).Elem()
 )
 
+// Status Code sent when the client closes the connection
+// before the request has been answered
+const StatusClientClosedRequest = 499
+
 // This method return true if the received type is an context type
 // It means that it doesn't need to be mapped and will be present in the context
 // It also return an error message if user used *http.ResponseWriter or used http.Request
-// Context types include error and []error types
+// Context types include error, []error, context.Context, api.ListParams and *api.Links types
 func isContextType(resourceType reflect.Type) bool {
 	// Test if user used *http.ResponseWriter insted of http.ResponseWriter
 	if resourceType.AssignableTo(responseWriterPtrType) {
@@ -46,6 +53,9 @@ func isContextType(resourceType reflect.Type) bool {
 		resourceType.AssignableTo(requestPtrType) ||
 		resourceType.AssignableTo(errorType) ||
 		resourceType.AssignableTo(errorSliceType) ||
+		resourceType == contextType ||
+		resourceType == listParamsType ||
+		resourceType == linksPtrType ||
 		resourceType.Implements(idInterfaceType)
 }
 
@@ -165,6 +175,12 @@ func isValidDependencyType(t reflect.Type) error {
 	return fmt.Errorf("Type %s is not allowed as dependency", t)
 }
 
+// Return true if the type t is injected with the Elem of the stored value of type vt
+// Structs and Slices are stored in a Ptr, and so are the provided values
+func isElemOf(t reflect.Type, vt reflect.Type) bool {
+	return vt != t && vt.Kind() == reflect.Ptr && vt.Elem() == t
+}
+
 // Return true if this Type is a Slice or Ptr to Slice
 func isSliceType(t reflect.Type) bool {
 	if t.Kind() == reflect.Ptr {
@@ -173,14 +189,17 @@ func isSliceType(t reflect.Type) bool {
 	return t.Kind() == reflect.Slice
 }
 
-// 'Init' methods should have no Inputs,
-// and it can just output an error
+// 'Init' methods can have just other Resources as Inputs,
+// and it can just output itself and an error
 func isValidInit(m reflect.Method) error {
 
-	if m.Type.NumIn() > 1 {
-		return fmt.Errorf("Resource %s has an invalid Init method %s. "+
-			" It should have no inputs\n",
-			m.Type.In(0), m.Type)
+	for i := 1; i < m.Type.NumIn(); i++ {
+		t := m.Type.In(i)
+		if isContextType(t) || isValidDependencyType(t) != nil {
+			return fmt.Errorf("Resource %s has an invalid Init method %s. "+
+				" It can't receive %s, just other Resources\n",
+				m.Type.In(0), m.Type, t)
+		}
 	}
 
 	if m.Type.NumOut() > 2 {
@@ -197,6 +216,10 @@ func isValidInit(m reflect.Method) error {
 		if elemOfType(t) == owner || t == errorType {
 			continue
 		}
+		// Promoted Inits output the embedded Resource
+		if _, embedded := embeddedIndex(owner, t); embedded {
+			continue
+		}
 
 		return fmt.Errorf("Resource %s has an invalid New method %s. "+
 			"It can't outputs %s\n", m.Type.In(0), m.Type, t)
@@ -225,6 +248,11 @@ func isValidConstructor(method reflect.Method) error {
 			itself = true
 			continue
 		}
+		// Promoted constructors output the embedded Resource
+		if _, embedded := embeddedIndex(owner, t); embedded && !itself {
+			itself = true
+			continue
+		}
 		if t == errorType && !err {
 			err = true
 			continue
@@ -286,14 +314,32 @@ func splitsMethodName(m *method) (string, string) {
 }
 
 // Write an error and Status Code in the ResponseWriter encoded as JSON,
+// Validation errors also list the rules violated
 func writeError(w http.ResponseWriter, err error, status int) {
+	response := map[string]interface{}{"error": err.Error()}
+
+	var verr *ViolationsError
+	if errors.As(err, &verr) {
+		response["violations"] = verr.Violations
+	}
+
 	// Encode the output in JSON
-	jsonResponse, err := json.MarshalIndent(map[string]string{"error": err.Error()}, "", "\t")
+	jsonResponse, err := json.MarshalIndent(response, "", "\t")
 	if err != nil {
 		http.Error(w, "{error: \"Error encoding the error message to Json: "+err.Error()+"\"}", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	w.Write(jsonResponse)
 }
+
+// Return the Status Code that answers a request whose context is done
+// Timeouts are answered as 503 and requests closed by the client as 499
+func contextErrorStatus(err error) int {
+	if errors.Is(err, gocontext.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+	return StatusClientClosedRequest
+}
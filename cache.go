@@ -0,0 +1,181 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Resources can declare how the clients and proxies cache their responses
+// The policy is answered in the Cache-Control and Vary headers
+// of the successful GET and HEAD responses
+// Children without a policy inherit the policy of their parents
+// Ex: `cache:"public,max-age=1h,stale-while-revalidate=30s" vary:"Accept-Language"`
+// or `cache:"no-store"` for the data that can't be cached
+//
+
+// How the responses can be cached
+type CachePolicy struct {
+	// The response can be stored by shared caches, like proxies
+	Public bool
+
+	// The response can be stored just by the client
+	Private bool
+
+	// The response can't be stored
+	NoStore bool
+
+	// The response should be revalidated before being used
+	NoCache bool
+
+	// How long the response is fresh
+	MaxAge time.Duration
+
+	// How long a stale response can be used while it is revalidated in background
+	StaleWhileRevalidate time.Duration
+
+	// The request headers that change the response
+	Vary []string
+}
+
+// Resources can implement this interface to define their cache policy
+// It receives the name of the mapped method, like GET or GETLike,
+// or an empty name when the policy is inherited by the children
+// If it returns false the policy of the 'cache' and 'vary' tags in the Resource field
+// is used, or the policy is inherited from the parents
+type CachePolicer interface {
+	CachePolicy(method string) (CachePolicy, bool)
+}
+
+var cachePolicerInterfaceType = reflect.TypeOf((*CachePolicer)(nil)).Elem()
+
+// Return the Cache-Control header of the policy
+func (p *CachePolicy) String() string {
+	if p.NoStore {
+		return "no-store"
+	}
+
+	directives := []string{}
+	if p.Public {
+		directives = append(directives, "public")
+	}
+	if p.Private {
+		directives = append(directives, "private")
+	}
+	if p.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if p.MaxAge > 0 {
+		directives = append(directives, fmt.Sprintf("max-age=%d", int(p.MaxAge.Seconds())))
+	}
+	if p.StaleWhileRevalidate > 0 {
+		directives = append(directives, fmt.Sprintf("stale-while-revalidate=%d", int(p.StaleWhileRevalidate.Seconds())))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// Write the Cache-Control and Vary headers of the policy
+func (p *CachePolicy) write(w http.ResponseWriter) {
+	if cc := p.String(); cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+	if len(p.Vary) > 0 {
+		w.Header().Set("Vary", strings.Join(p.Vary, ", "))
+	}
+}
+
+// Return the cache policy of the mapped method of the Resource
+// The policy returned by the CachePolicer for the method comes first,
+// then the one of the tags, then the one inherited from its parents
+// It returns nil if no one declares a policy
+func (r *resource) cachePolicy(method string) (*CachePolicy, error) {
+	if r.value.Type().Implements(cachePolicerInterfaceType) {
+		p, ok := r.value.Interface().(CachePolicer).CachePolicy(method)
+		if ok {
+			return &p, nil
+		}
+	}
+
+	p, defined, err := parseCachePolicy(r.tag)
+	if err != nil {
+		return nil, fmt.Errorf("The Resource %s has an invalid cache policy: %s", r.value.Type(), err)
+	}
+	if defined {
+		return p, nil
+	}
+
+	if r.parent == nil {
+		return nil, nil
+	}
+	return r.parent.cachePolicy("")
+}
+
+// Parse the 'cache' and 'vary' tags of a Resource field
+func parseCachePolicy(tag reflect.StructTag) (*CachePolicy, bool, error) {
+	cache, cacheDefined := tag.Lookup("cache")
+	vary, varyDefined := tag.Lookup("vary")
+	if !cacheDefined && !varyDefined {
+		return nil, false, nil
+	}
+
+	p := &CachePolicy{}
+
+	for _, directive := range strings.Split(cache, ",") {
+		parts := strings.SplitN(strings.TrimSpace(directive), "=", 2)
+		name, value := parts[0], ""
+		if len(parts) > 1 {
+			value = parts[1]
+		}
+
+		var err error
+		switch name {
+		case "":
+		case "public":
+			p.Public = true
+		case "private":
+			p.Private = true
+		case "no-store":
+			p.NoStore = true
+		case "no-cache":
+			p.NoCache = true
+		case "max-age":
+			p.MaxAge, err = parseCacheDuration(value)
+		case "stale-while-revalidate":
+			p.StaleWhileRevalidate, err = parseCacheDuration(value)
+		default:
+			return nil, true, fmt.Errorf("unknown cache directive '%s'", name)
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid %s '%s'", name, value)
+		}
+	}
+
+	if p.Public && p.Private {
+		return nil, true, fmt.Errorf("it can't be public and private")
+	}
+
+	for _, header := range strings.Split(vary, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			p.Vary = append(p.Vary, http.CanonicalHeaderKey(header))
+		}
+	}
+
+	return p, true, nil
+}
+
+// Parse a duration like 1h or 30s, or just the seconds like 3600
+func parseCacheDuration(value string) (time.Duration, error) {
+	seconds, err := strconv.Atoi(value)
+	if err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %s", value)
+	}
+	return d, nil
+}
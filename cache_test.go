@@ -0,0 +1,92 @@
+// This package tests the cache policies of the Resources
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+type Portal struct {
+	Release Release `cache:"public,max-age=1h,stale-while-revalidate=30" vary:"accept-language"`
+	Wallet  Wallet  `cache:"no-store"`
+	Lobby   Lobby   `cache:"public,max-age=60"`
+}
+
+type Release struct {
+	Notes Notes
+}
+
+func (r *Release) GET() string {
+	return "v1"
+}
+
+func (r *Release) POST() string {
+	return "released"
+}
+
+// Children inherit the policy of their parents
+type Notes struct{}
+
+func (n *Notes) GET() string {
+	return "notes"
+}
+
+type Wallet struct{}
+
+func (w *Wallet) GET() string {
+	return "secret"
+}
+
+type Lobby struct{}
+
+// Methods can have their own policies, that come before the tags
+func (l *Lobby) CachePolicy(method string) (CachePolicy, bool) {
+	if method == "GETNews" {
+		return CachePolicy{Private: true, NoCache: true}, true
+	}
+	return CachePolicy{}, false
+}
+
+func (l *Lobby) GET() string {
+	return "lobby"
+}
+
+func (l *Lobby) GETNews() string {
+	return "news"
+}
+
+type BrokenCache struct {
+	Lobby Lobby `cache:"forever"`
+}
+
+func TestCachePolicy(t *testing.T) {
+	rt, err := NewRouter(Portal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		method, path, cacheControl, vary string
+	}{
+		{"GET", "/portal/release", "public, max-age=3600, stale-while-revalidate=30", "Accept-Language"},
+		{"GET", "/portal/release/notes", "public, max-age=3600, stale-while-revalidate=30", "Accept-Language"},
+		{"POST", "/portal/release", "", ""},
+		{"GET", "/portal/wallet", "no-store", ""},
+		{"GET", "/portal/lobby", "public, max-age=60", ""},
+		{"GET", "/portal/lobby/news", "private, no-cache", ""},
+	}
+	for _, c := range cases {
+		h := doRequest(rt, c.method, c.path, "").Header()
+		if h.Get("Cache-Control") != c.cacheControl || h.Get("Vary") != c.vary {
+			t.Errorf("Expected Cache-Control %q and Vary %q for [%s] %s, got %q and %q",
+				c.cacheControl, c.vary, c.method, c.path, h.Get("Cache-Control"), h.Get("Vary"))
+		}
+	}
+}
+
+func TestInvalidCachePolicy(t *testing.T) {
+	_, err := NewRouter(BrokenCache{})
+	if err == nil || !strings.Contains(err.Error(), "forever") {
+		t.Errorf("Expected an error for the invalid cache policy, got %v", err)
+	}
+}
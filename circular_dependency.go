@@ -68,7 +68,7 @@ func (cd *circularDependency) checkDependency(d *dependency, m *method) error {
 			//log.Println("CD for Dependency New Dependency", i, t, dependency.isType(t))
 
 			// The first element will always be the dependency itself
-			if d.isType(t) {
+			if i == 0 || d.isType(t) {
 				continue
 			}
 
@@ -91,6 +91,14 @@ func (cd *circularDependency) checkDependency(d *dependency, m *method) error {
 		}
 	}
 
+	// Singletons can't depend on request scoped values
+	if d.scope == SingletonScope {
+		err := checkSingletonDependency(d, m)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove itself from the list
 	cd.pop()
 
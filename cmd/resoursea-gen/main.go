@@ -0,0 +1,134 @@
+// Command resoursea-gen generates a Router for a Resource tree
+// that calls the constructors and the mapped methods directly, without reflection.
+//
+// Add this line in the package that declares the root Resource and run go generate:
+//
+//	//go:generate resoursea-gen -type API
+//
+// It creates the file api_router_gen.go with the APIRouter type and the
+// NewAPIRouter function, that receives the initial state of the Resource tree.
+// The generator needs to import the package, so it can't be the main package.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeName = flag.String("type", "", "the root Resource type, required")
+	name     = flag.String("name", "", "the name of the root Resource, defaults to the type name")
+	tag      = flag.String("tag", "", "the tag of the root Resource")
+	output   = flag.String("output", "", "the generated file, defaults to <type>_router_gen.go")
+)
+
+// The program that imports the package and generates the Router
+const program = `package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/resoursea/api"
+	target %q
+)
+
+func main() {
+	f, err := os.Create(%q)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = api.Generate(f, %q, target.%s{}, %s)
+	f.Close()
+	if err != nil {
+		os.Remove(%q)
+		log.Fatalln(err)
+	}
+}
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("resoursea-gen: ")
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// The temporary files are removed by run before exiting
+	err := run()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// Generate the Router running a program that imports the package
+func run() error {
+
+	// Find the package being generated
+	out, err := exec.Command("go", "list", "-f", "{{.ImportPath}} {{.Name}}", ".").Output()
+	if err != nil {
+		return fmt.Errorf("Can't list the package: %s", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return fmt.Errorf("Can't list the package: %s", out)
+	}
+	importPath, pkg := fields[0], fields[1]
+
+	if pkg == "main" {
+		return fmt.Errorf("The Resources can't be declared in the main package, it can't be imported")
+	}
+
+	file := *output
+	if file == "" {
+		file = strings.ToLower(*typeName) + "_router_gen.go"
+	}
+	file, err = filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	// An outdated generated file could break the package build
+	os.Remove(file)
+
+	args := []string{}
+	if *name != "" || *tag != "" {
+		n := *name
+		if n == "" {
+			n = *typeName
+		}
+		args = append(args, fmt.Sprintf("%q", n), fmt.Sprintf("%q", *tag))
+	}
+
+	// The program should be inside the module to import the package
+	dir, err := os.MkdirTemp(".", "resoursea_gen_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := fmt.Sprintf(program, importPath, file, pkg, *typeName, strings.Join(args, ", "), file)
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644)
+	if err != nil {
+		return err
+	}
+
+	// The program reports its own errors
+	cmd := exec.Command("go", "run", "./"+filepath.Base(dir))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("The Router of %s wasn't generated: %s", *typeName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+//
+// Slices whose Elem has a field tagged with `collection:"id"` are collections
+// Each element of the slice keeps its own initial state, and the element
+// injected in the requests is the one whose ID field matches the ID in the URI
+// Every element is initialized by its own Init
+//
+
+// Returned when the URI asks for an element that doesn't exist in the collection
+// It is answered as 404
+type ElementNotFoundError struct {
+	Type reflect.Type
+	ID   string
+}
+
+func (e *ElementNotFoundError) Error() string {
+	return fmt.Sprintf("Not found any %s with the ID %s", e.Type, e.ID)
+}
+
+func (e *ElementNotFoundError) StatusCode() int {
+	return http.StatusNotFound
+}
+
+// The initial state of the elements of a slice Resource
+type collection struct {
+	// Ptr to the slice that stores the elements
+	slice reflect.Value
+
+	// Index of the ID field in the Elem
+	idField []int
+
+	// Position of each element in the slice, indexed by its ID
+	positions map[string]int
+}
+
+// Return the index of the field tagged as the collection ID, if any
+func collectionIDField(t reflect.Type) ([]int, bool) {
+	t = elemOfType(t)
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("collection") == "id" {
+			return t.Field(i).Index, true
+		}
+	}
+	return nil, false
+}
+
+// Creates the collection of the slice Resource value, if its Elem has an ID field
+// The slice is copied, so the elements can be initialized
+// without changing the value received by NewRouter
+func newCollection(value reflect.Value) *collection {
+	sliceValue := elemOfValue(value)
+
+	idField, ok := collectionIDField(sliceValue.Type().Elem())
+	if !ok {
+		return nil
+	}
+
+	copied := reflect.MakeSlice(sliceValue.Type(), sliceValue.Len(), sliceValue.Len())
+	for i := 0; i < sliceValue.Len(); i++ {
+		elem := sliceValue.Index(i)
+		if elem.Kind() == reflect.Ptr && !elem.IsNil() {
+			elem = ptrOfValue(elem)
+		}
+		copied.Index(i).Set(elem)
+	}
+	value.Elem().Set(copied)
+
+	return &collection{
+		slice:   value,
+		idField: idField,
+	}
+}
+
+// Return the number of elements
+func (col *collection) len() int {
+	return col.slice.Elem().Len()
+}
+
+// Return the Ptr to the element at the position
+func (col *collection) element(i int) reflect.Value {
+	elem := col.slice.Elem().Index(i)
+	if elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		return elem
+	}
+	return elem.Addr()
+}
+
+// Replace the element at the position
+func (col *collection) set(i int, v reflect.Value) {
+	col.element(i).Elem().Set(elemOfValue(v))
+}
+
+// Index the elements by their IDs
+// It should be called after the elements change, like after their Inits
+func (col *collection) index() error {
+	col.positions = make(map[string]int, col.len())
+	for i := 0; i < col.len(); i++ {
+		id := fmt.Sprint(col.element(i).Elem().FieldByIndex(col.idField).Interface())
+		if _, exist := col.positions[id]; exist {
+			return fmt.Errorf("The collection %s has more than one element with the ID %s",
+				col.slice.Type().Elem(), id)
+		}
+		col.positions[id] = i
+	}
+	return nil
+}
+
+// Return a copy of the element with the ID
+func (col *collection) find(t reflect.Type, id string) (reflect.Value, error) {
+	i, exist := col.positions[id]
+	if !exist {
+		return reflect.Value{}, &ElementNotFoundError{Type: t, ID: id}
+	}
+	return ptrOfValue(col.element(i)), nil
+}
+
+// Call the Init method of the Resource in each element of its collection
+// The inputs but the first one, the element itself, are shared by all calls
+func (r *resource) runElementInits(inputs []reflect.Value) error {
+	col := r.parent.collection
+
+	for i := 0; i < col.len(); i++ {
+		elem := col.element(i)
+
+		// The Init could be attached to the Elem insted of the Ptr
+		inputs[0] = elem
+		if r.init.Type.In(0).Kind() != reflect.Ptr {
+			inputs[0] = elem.Elem()
+		}
+
+		out := r.init.Func.Call(inputs)
+		for _, v := range out {
+			if v.Type() == errorType {
+				if !v.IsNil() {
+					return v.Interface().(error)
+				}
+				continue
+			}
+			if ptrOfType(v.Type()) == r.value.Type() {
+				if v.Kind() != reflect.Ptr || !v.IsNil() {
+					col.set(i, v)
+				}
+				continue
+			}
+			setPromotedOutput(elem, v)
+		}
+	}
+
+	return col.index()
+}
+
+// Return true if the Resource is the Elem of a collection
+func (r *resource) isElement() bool {
+	return r.parent != nil && r.parent.collection != nil
+}
+
+// Return the initial value of the dependency for this request
+// The elements of the collections and of the Stores are found by the ID in the URI,
+// if the URI has no ID for it, the initial value of the Elem is used
+func (c *context) initialValue(d *dependency) (reflect.Value, error) {
+	if d.collection == nil && d.store == nil {
+		return d.new(), nil
+	}
+
+	v, exist := c.idMap[d.value.Type()]
+	if !exist || v.IsNil() {
+		return d.new(), nil
+	}
+
+	id := v.Interface().(ID).String()
+	if d.store != nil {
+		return d.stored(id)
+	}
+	return d.collection.find(d.value.Type(), id)
+}
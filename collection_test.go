@@ -0,0 +1,105 @@
+// This package tests the slices of Resources as collections
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Solar struct {
+	Planets Planets
+}
+
+type Planets []Planet
+
+type Planet struct {
+	Name     string `collection:"id"`
+	Moons    int
+	Greeting string
+	Visitor  string
+}
+
+func (p *Planet) Init() {
+	p.Greeting = "Hello " + p.Name
+}
+
+func (p *Planet) New(req *http.Request) {
+	p.Visitor = req.Header.Get("Visitor")
+}
+
+func (p Planet) GET() Planet {
+	return p
+}
+
+type Twins struct {
+	Planets Planets
+}
+
+func getPlanet(t *testing.T, rt Router, path string) (*httptest.ResponseRecorder, Planet) {
+	w := doRequest(rt, "GET", path, "", "Visitor", "gopher")
+
+	var resp struct{ Planet Planet }
+	if w.Code == http.StatusOK {
+		decodeBody(t, w, &resp)
+	}
+	return w, resp.Planet
+}
+
+func TestCollection(t *testing.T) {
+	solar := Solar{Planets: Planets{{Name: "earth", Moons: 1}, {Name: "mars", Moons: 2}}}
+
+	rt, err := NewRouter(solar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each element keeps its own state and runs its own Init
+	for _, expected := range solar.Planets {
+		_, p := getPlanet(t, rt, "/solar/planets/"+expected.Name)
+		if p.Name != expected.Name || p.Moons != expected.Moons {
+			t.Errorf("Expected the element %s, got %+v", expected.Name, p)
+		}
+		if p.Greeting != "Hello "+expected.Name {
+			t.Errorf("Expected the element initialized by its own Init, got %q", p.Greeting)
+		}
+		if p.Visitor != "gopher" {
+			t.Errorf("Expected the element constructed by New, got %+v", p)
+		}
+	}
+
+	// The value received by NewRouter isn't changed by the Inits
+	if solar.Planets[0].Greeting != "" {
+		t.Errorf("Expected the original slice untouched, got %+v", solar.Planets[0])
+	}
+
+	// Unknown IDs are not found
+	w, _ := getPlanet(t, rt, "/solar/planets/pluto")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an unknown ID, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "pluto") {
+		t.Errorf("Expected the unknown ID in the error, got %s", w.Body.String())
+	}
+}
+
+func TestCollectionDuplicatedID(t *testing.T) {
+	_, err := NewRouter(Twins{Planets: Planets{{Name: "earth"}, {Name: "earth"}}})
+	if err == nil || !strings.Contains(err.Error(), "earth") {
+		t.Errorf("Expected an error for the duplicated ID, got %v", err)
+	}
+}
+
+func TestElementNotFoundError(t *testing.T) {
+	var err error = &DependencyError{Err: &ElementNotFoundError{ID: "pluto"}}
+
+	var notFound *ElementNotFoundError
+	if !errors.As(err, &notFound) || notFound.ID != "pluto" {
+		t.Errorf("Expected the ElementNotFoundError to be wrapped, got %v", err)
+	}
+	if errorStatus(err) != http.StatusNotFound {
+		t.Errorf("Expected the status 404, got %d", errorStatus(err))
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	gocontext "context"
+	"reflect"
+	"sync"
+)
+
+// Result of the concurrent execution of a step
+type stepResult struct {
+	errors      []reflect.Value
+	constructed []reflect.Value
+	failure     error
+	panicked    bool
+	panicValue  interface{}
+}
+
+// Execute the steps of the method plan in parallel goroutines
+// Each step runs just after the steps it requires are done
+// The errors are added to the context in the plan order
+// When a step stops the request, the steps after it in the plan order are cancelled,
+// the ones before it still run, so the request fails with the same error it would
+// fail with if the steps ran one by one
+func (c *context) runStepsConcurrently() {
+	steps := c.method.plan.steps
+
+	ctxs := make([]gocontext.Context, len(steps))
+	cancels := make([]gocontext.CancelFunc, len(steps))
+	for i := range steps {
+		ctxs[i], cancels[i] = gocontext.WithCancel(c.ctx)
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	results := make([]stepResult, len(steps))
+	done := make([]chan struct{}, len(steps))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, s := range steps {
+		wg.Add(1)
+		go func(i int, s *step) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range s.requires {
+				<-done[j]
+			}
+
+			// A failure stops just the steps after it in the plan order
+			results[i] = c.runStepAlone(ctxs[i], s, results)
+			if results[i].failure != nil {
+				for _, cancel := range cancels[i+1:] {
+					cancel()
+				}
+			}
+		}(i, s)
+	}
+	wg.Wait()
+
+	for i, s := range steps {
+		r := results[i]
+
+		// Panics in the goroutines are raised in the request goroutine
+		if r.panicked {
+			c.constructing = s.dependency.value.Type()
+			panic(r.panicValue)
+		}
+
+		// The request fails with the failure of the first step in the plan order,
+		// the same one it would fail with if the steps ran one by one
+		if r.failure != nil && c.failure == nil {
+			c.failure = r.failure
+		}
+
+		c.errors = append(c.errors, r.errors...)
+		c.constructed = append(c.constructed, r.constructed...)
+	}
+}
+
+// Execute the step in its own context
+// It shares the slots, but each step writes just its own slot
+// It can see just the errors of the steps it requires
+func (c *context) runStepAlone(ctx gocontext.Context, s *step, results []stepResult) (r stepResult) {
+
+	defer func() {
+		if value := recover(); value != nil {
+			r = stepResult{panicked: true, panicValue: value}
+		}
+	}()
+
+	sub := &context{
+		method:      c.method,
+		ctx:         ctx,
+		values:      c.values,
+		idMap:       c.idMap,
+		errors:      []reflect.Value{},
+		constructed: []reflect.Value{},
+		slots:       c.slots,
+		list:        c.list,
+		links:       c.links,
+	}
+
+	for _, j := range s.requires {
+		sub.errors = append(sub.errors, results[j].errors...)
+	}
+	previous := len(sub.errors)
+
+	sub.runStep(s)
+
+	r.errors = sub.errors[previous:]
+	r.constructed = sub.constructed
+	r.failure = sub.failure
+
+	return r
+}
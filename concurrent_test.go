@@ -0,0 +1,192 @@
+// This package tests the concurrent construction of dependencies
+package api
+
+import (
+	gocontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// The three constructors wait for each other, so they return just
+// if they are constructed concurrently, otherwise the test never ends
+var constructing sync.WaitGroup
+
+type Dashboard struct {
+	Summary  Summary
+	Outage   Outage
+	Incident Incident
+}
+
+type RemoteConfig struct{}
+
+func (r *RemoteConfig) New() (*RemoteConfig, error) {
+	constructing.Done()
+	constructing.Wait()
+	return r, errors.New("config")
+}
+
+type CacheWarmup struct{}
+
+func (c *CacheWarmup) New() (*CacheWarmup, error) {
+	constructing.Done()
+	constructing.Wait()
+	return c, errors.New("cache")
+}
+
+type Lookup struct{}
+
+func (l *Lookup) New() (*Lookup, error) {
+	constructing.Done()
+	constructing.Wait()
+	return l, errors.New("lookup")
+}
+
+type Summary struct {
+	Errors []string
+}
+
+// Waits for all its dependencies and collects its errors
+func (s *Summary) New(_ *RemoteConfig, _ *CacheWarmup, _ *Lookup, errs []error) *Summary {
+	for _, err := range errs {
+		s.Errors = append(s.Errors, err.Error())
+	}
+	return s
+}
+
+func (s *Summary) GET() *Summary {
+	return s
+}
+
+func TestConstructConcurrently(t *testing.T) {
+	rt, err := NewRouter(Dashboard{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ConstructConcurrently(true)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/dashboard/summary", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each constructor returns just after the three started
+	constructing.Add(3)
+	rt.ServeHTTP(w, req)
+
+	if w.Body.String() != "{\n\t\"Summary\": {\n\t\t\"Errors\": [\n\t\t\t\"config\",\n\t\t\t\"cache\",\n\t\t\t\"lookup\"\n\t\t]\n\t}\n}" {
+		t.Fatalf("Errors weren't collected in the construction order: %s", w.Body)
+	}
+}
+
+// Barriers to make the standby fail while the mirror is constructed
+var mirrorStarted, standbyFailed chan struct{}
+
+type Mirror struct{}
+
+// Fails just after the standby, but comes first in the plan
+func (m *Mirror) New() (*Mirror, error) {
+	close(mirrorStarted)
+	<-standbyFailed
+	return m, errors.New("mirror down")
+}
+
+type Standby struct{}
+
+func (s *Standby) New() (*Standby, error) {
+	<-mirrorStarted
+	defer close(standbyFailed)
+	return s, notFound{}
+}
+
+type Outage struct{}
+
+func (o *Outage) GET(_ *Mirror, _ *Standby) string {
+	return "garbage"
+}
+
+// Barriers to make the probe fail while the watcher is constructed,
+// and the archive start after the watcher was cancelled
+var watcherStarted, watcherCancelled chan struct{}
+
+type Archive struct{}
+
+// Starts just after the probe failed, but comes first in the plan
+func (a *Archive) New(_ *Snapshot) (*Archive, error) {
+	return a, errors.New("archive down")
+}
+
+type Snapshot struct{}
+
+func (s *Snapshot) New() *Snapshot {
+	<-watcherCancelled
+	return s
+}
+
+type Probe struct{}
+
+func (p *Probe) New() (*Probe, error) {
+	<-watcherStarted
+	return p, notFound{}
+}
+
+// Comes after the probe in the plan, so its failure cancels it
+type Watcher struct{}
+
+func (w *Watcher) New(ctx gocontext.Context) *Watcher {
+	close(watcherStarted)
+	<-ctx.Done()
+	close(watcherCancelled)
+	return w
+}
+
+type Incident struct{}
+
+func (i *Incident) GET(_ *Archive, _ *Probe, _ *Watcher) string {
+	return "garbage"
+}
+
+func TestConcurrentFailureOrder(t *testing.T) {
+	rt, err := NewRouter(Dashboard{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.ConstructConcurrently(true)
+
+	for i := 0; i < 10; i++ {
+		mirrorStarted, standbyFailed = make(chan struct{}), make(chan struct{})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/dashboard/outage", nil)
+		rt.ServeHTTP(w, req)
+
+		// The request fails with the first failure in the plan order,
+		// not with the first one to happen
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected the failure of the mirror, got %d: %s", w.Code, w.Body)
+		}
+	}
+}
+
+func TestConcurrentFailureCancelsLaterSteps(t *testing.T) {
+	rt, err := NewRouter(Dashboard{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.ConstructConcurrently(true)
+
+	watcherStarted, watcherCancelled = make(chan struct{}), make(chan struct{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/dashboard/incident", nil)
+	rt.ServeHTTP(w, req)
+
+	// The archive starts after the probe failed, but it is still constructed
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the failure of the archive, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options used to create the Router: the sources of the initial state of the Resources,
+// the providers of the dependencies and the test doubles replacing them
+// Fields tagged with `env:"DB_URL"` are filled from the environment variables
+// and fields tagged with `config:"db.url"` from the config files
+// The environment variables have priority over the config files,
+// and both over the value passed to NewRouter
+// Fields tagged with `default:"..."` receive this value if nothing else was defined
+// Add the options required or secret to the env or config tags,
+// like `env:"DB_PASSWORD,required,secret"`, secret values are never reported
+type Config struct {
+	// Files read in order, the later ones override the earlier ones
+	// The format is defined by the extension: .json, .toml, .yaml or .yml
+	Files []string
+
+	// Return the value of the environment variable, os.LookupEnv is used if nil
+	LookupEnv func(key string) (string, bool)
+
+	// Test doubles injected in place of the dependencies of these types
+	// The types can be interfaces or structs, each double should implement
+	// the interface or be the struct, and is shared by all requests
+	Overrides map[reflect.Type]interface{}
+
+	// Functions providing the dependencies that can't be Resources
+	Providers []Provider
+}
+
+// Returned when some configured fields are missing or invalid
+// It lists all the problems found in the Resource tree
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "Invalid configuration:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// Fills the configured fields of the Resources
+type loader struct {
+	lookupEnv func(key string) (string, bool)
+
+	// Values read from the config files, indexed by its dotted key
+	// Lists have many values
+	values map[string][]string
+
+	problems []string
+}
+
+// Creates the loader reading all the config files
+func newLoader(config *Config) (*loader, error) {
+	ld := &loader{
+		lookupEnv: os.LookupEnv,
+		values:    map[string][]string{},
+		problems:  []string{},
+	}
+
+	if config == nil {
+		return ld, nil
+	}
+
+	if config.LookupEnv != nil {
+		ld.lookupEnv = config.LookupEnv
+	}
+
+	for _, file := range config.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var values map[string][]string
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			values, err = parseJSONConfig(data)
+		case ".toml":
+			values, err = parseTOMLConfig(data)
+		case ".yaml", ".yml":
+			values, err = parseYAMLConfig(data)
+		default:
+			err = fmt.Errorf("Unknown config file format %s", filepath.Ext(file))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the config file %s: %s", file, err)
+		}
+
+		for k, v := range values {
+			ld.values[k] = v
+		}
+	}
+
+	return ld, nil
+}
+
+// Return the test doubles of the config as singletons already built
+func newOverrides(config *Config) (map[reflect.Type]*singleton, error) {
+	overrides := map[reflect.Type]*singleton{}
+	if config == nil {
+		return overrides, nil
+	}
+
+	for t, double := range config.Overrides {
+		v := reflect.ValueOf(double)
+		if !v.IsValid() || v.Kind() != reflect.Ptr && v.Kind() != reflect.Struct ||
+			elemOfType(v.Type()).Kind() != reflect.Struct {
+			return nil, fmt.Errorf("The double %T of %s should be a struct or a pointer to struct", double, t)
+		}
+
+		// The dependencies are stored as pointers, the same double is shared
+		if v.Kind() == reflect.Struct {
+			v = ptrOfValue(v)
+		}
+		if v.IsNil() {
+			return nil, fmt.Errorf("The double of %s is nil", t)
+		}
+
+		if t.Kind() == reflect.Interface && !v.Type().Implements(t) ||
+			t.Kind() != reflect.Interface && v.Type() != ptrOfType(t) {
+			return nil, fmt.Errorf("The double %s can't replace %s", v.Type(), t)
+		}
+
+		overrides[overrideKey(t)] = &singleton{built: true, value: v}
+	}
+
+	return overrides, nil
+}
+
+// Struct dependencies can be requested as values or pointers,
+// so the doubles of structs are indexed by the pointer type
+func overrideKey(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Interface {
+		return t
+	}
+	return ptrOfType(t)
+}
+
+// Return the error listing all the problems found, or nil
+func (ld *loader) err() error {
+	if len(ld.problems) == 0 {
+		return nil
+	}
+	return &ConfigError{Problems: ld.problems}
+}
+
+// Options of the env and config tags of a field
+type configTag struct {
+	env      string
+	key      string
+	def      string
+	hasDef   bool
+	required bool
+	secret   bool
+}
+
+// Parse the env, config and default tags of the field
+// Return false if the field isn't configured
+func parseConfigTag(tag reflect.StructTag) (configTag, bool) {
+	ct := configTag{}
+
+	env, hasEnv := tag.Lookup("env")
+	key, hasKey := tag.Lookup("config")
+	ct.def, ct.hasDef = tag.Lookup("default")
+
+	for _, value := range []string{env, key} {
+		for _, option := range strings.Split(value, ",")[1:] {
+			switch option {
+			case "required":
+				ct.required = true
+			case "secret":
+				ct.secret = true
+			}
+		}
+	}
+
+	ct.env = strings.Split(env, ",")[0]
+	ct.key = strings.Split(key, ",")[0]
+
+	return ct, hasEnv || hasKey || ct.hasDef
+}
+
+// Fill the configured fields of the Resource
+// Return false if some value is missing or invalid
+func (ld *loader) load(r *resource) bool {
+
+	// The Elem of the slices are just the initial value of its Elems
+	if r.parent != nil && r.parent.isSlice {
+		return true
+	}
+
+	v := r.value.Elem()
+	if v.Kind() != reflect.Struct {
+		return true
+	}
+
+	ok := true
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		ct, configured := parseConfigTag(field.Tag)
+		if !configured {
+			continue
+		}
+
+		problem := ld.loadField(v.Field(i), field, ct)
+		if problem != "" {
+			ld.problems = append(ld.problems, fmt.Sprintf("%s: %s", r.path(), problem))
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// Fill the field from the sources, returning the problem found
+func (ld *loader) loadField(fv reflect.Value, field reflect.StructField, ct configTag) string {
+
+	if !fv.CanSet() {
+		return fmt.Sprintf("the field %s is configured but it isn't exported", field.Name)
+	}
+
+	var values []string
+	source := ""
+
+	if ct.key != "" {
+		if v, exist := ld.values[ct.key]; exist {
+			values, source = v, "config "+ct.key
+		}
+	}
+
+	if ct.env != "" {
+		if v, exist := ld.lookupEnv(ct.env); exist {
+			values, source = []string{v}, "env "+ct.env
+			if fv.Kind() == reflect.Slice {
+				values = strings.Split(v, ",")
+			}
+		}
+	}
+
+	if source == "" {
+		if !fv.IsZero() {
+			return ""
+		}
+		if ct.hasDef {
+			values, source = []string{ct.def}, "default"
+			if fv.Kind() == reflect.Slice {
+				values = strings.Split(ct.def, ",")
+			}
+		} else if ct.required {
+			return fmt.Sprintf("the field %s is required, set %s", field.Name, ct.names())
+		} else {
+			return ""
+		}
+	}
+
+	err := setConfigValue(fv, values)
+	if err != nil {
+		// The parse errors usually repeat the value, so they aren't reported for secrets
+		if ct.secret {
+			return fmt.Sprintf("invalid value '<redacted>' for the field %s from %s", field.Name, source)
+		}
+		return fmt.Sprintf("invalid value '%s' for the field %s from %s: %s",
+			strings.Join(values, ","), field.Name, source, err)
+	}
+
+	return ""
+}
+
+// Return the names of the sources of the field
+func (ct configTag) names() string {
+	names := []string{}
+	if ct.env != "" {
+		names = append(names, "the env "+ct.env)
+	}
+	if ct.key != "" {
+		names = append(names, "the config "+ct.key)
+	}
+	return strings.Join(names, " or ")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Set the field with the values parsed for its type
+// Slices receives all values, the other types just one
+func setConfigValue(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			err := setConfigScalar(slice.Index(i), strings.TrimSpace(value))
+			if err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	if len(values) != 1 {
+		return fmt.Errorf("expected one value, found a list")
+	}
+	return setConfigScalar(fv, values[0])
+}
+
+func setConfigScalar(fv reflect.Value, value string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("the type %s can't be configured", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//
+// The config files are flattened into a map of dotted keys,
+// like db.url, where each key has the list of its values
+// Scalars have just one value, and lists have one value for each item
+//
+// Just the commonly used subset of TOML and YAML is supported:
+// tables, nested maps, scalars, quoted strings and lists of scalars
+// The other syntaxes are rejected with the line where they were found,
+// like arrays of tables, multi-line values, anchors or maps inside lists
+//
+
+// Parse a JSON config file
+func parseJSONConfig(data []byte) (map[string][]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc map[string]interface{}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string][]string{}
+	err = flattenJSON("", doc, values)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenJSON(key string, v interface{}, values map[string][]string) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			err := flattenJSON(joinKey(key, k), item, values)
+			if err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("the list %s should have just scalar values", key)
+			}
+			list[i] = fmt.Sprint(item)
+		}
+		values[key] = list
+	case nil:
+	default:
+		values[key] = []string{fmt.Sprint(v)}
+	}
+	return nil
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Parse a TOML config file
+func parseTOMLConfig(data []byte) (map[string][]string, error) {
+	values := map[string][]string{}
+	table := ""
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, fmt.Errorf("line %d: arrays of tables aren't supported", n+1)
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table %s", n+1, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+
+		key := unquoteKey(strings.TrimSpace(parts[0]))
+		list, err := parseScalars(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n+1, err)
+		}
+		values[joinKey(table, key)] = list
+	}
+
+	return values, nil
+}
+
+// Parse a YAML config file
+func parseYAMLConfig(data []byte) (map[string][]string, error) {
+	values := map[string][]string{}
+
+	// The keys of the maps still open, with its indentation
+	type level struct {
+		indent int
+		key    string
+	}
+	levels := []level{}
+
+	// The key waiting for its list items
+	listKey := ""
+
+	// The indentation of the last scalar, the lines deeper than it would continue it
+	scalarIndent := -1
+
+	started := false
+
+	for n, line := range strings.Split(string(data), "\n") {
+		content := strings.TrimSpace(stripComment(line))
+		if content == "" {
+			continue
+		}
+		if content == "---" {
+			if started {
+				return nil, fmt.Errorf("line %d: multiple documents aren't supported", n+1)
+			}
+			continue
+		}
+		started = true
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs can't be used for indentation", n+1)
+		}
+		if scalarIndent >= 0 && indent > scalarIndent {
+			return nil, fmt.Errorf("line %d: multi-line values aren't supported", n+1)
+		}
+		scalarIndent = -1
+
+		for len(levels) > 0 && levels[len(levels)-1].indent >= indent {
+			levels = levels[:len(levels)-1]
+		}
+
+		if strings.HasPrefix(content, "- ") || content == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", n+1)
+			}
+			item, err := parseYAMLScalar(strings.TrimSpace(strings.TrimPrefix(content, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", n+1, err)
+			}
+			values[listKey] = append(values[listKey], item)
+			scalarIndent = indent
+			continue
+		}
+
+		parts := strings.SplitN(content, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+
+		prefix := ""
+		for _, l := range levels {
+			prefix = joinKey(prefix, l.key)
+		}
+		key := unquoteKey(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		// A key without value opens a map or a list
+		if value == "" {
+			levels = append(levels, level{indent: indent, key: key})
+			listKey = joinKey(prefix, key)
+			continue
+		}
+		listKey = ""
+
+		err := checkYAMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n+1, err)
+		}
+		list, err := parseScalars(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n+1, err)
+		}
+		values[joinKey(prefix, key)] = list
+		scalarIndent = indent
+	}
+
+	return values, nil
+}
+
+// Return an error if the YAML value uses a syntax that isn't supported
+func checkYAMLValue(value string) error {
+	switch value[0] {
+	case '|', '>':
+		return fmt.Errorf("multi-line strings aren't supported")
+	case '&', '*', '!':
+		return fmt.Errorf("anchors, aliases and tags aren't supported")
+	}
+	return nil
+}
+
+// Parse a YAML list item, that should be a scalar
+func parseYAMLScalar(item string) (string, error) {
+	if item == "" {
+		return "", fmt.Errorf("empty list items aren't supported")
+	}
+	err := checkYAMLValue(item)
+	if err != nil {
+		return "", err
+	}
+	if item[0] == '[' || item == "-" || strings.HasPrefix(item, "- ") {
+		return "", fmt.Errorf("lists inside lists aren't supported")
+	}
+	if item[0] != '"' && item[0] != '\'' && (strings.Contains(item, ": ") || strings.HasSuffix(item, ":")) {
+		return "", fmt.Errorf("maps inside lists aren't supported")
+	}
+	return parseScalar(item)
+}
+
+// Remove the comment started by # outside of quotes
+func stripComment(line string) string {
+	quote := rune(0)
+	for i, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+// Parse a scalar or an inline list of scalars, like [1, 2, 3]
+func parseScalars(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") {
+		item, err := parseScalar(value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{item}, nil
+	}
+
+	if !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("lists should be written in a single line")
+	}
+
+	list := []string{}
+	for _, item := range splitList(value[1 : len(value)-1]) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.HasPrefix(item, "[") || strings.HasPrefix(item, "{") {
+			return nil, fmt.Errorf("the list should have just scalar values")
+		}
+		s, err := parseScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Split the items of an inline list by the commas outside of quotes
+func splitList(s string) []string {
+	items := []string{}
+	quote := rune(0)
+	start := 0
+	for i, c := range s {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// Parse a plain, 'single' or "double" quoted scalar
+func parseScalar(value string) (string, error) {
+	if strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''") {
+		return "", fmt.Errorf("multi-line strings aren't supported")
+	}
+	if strings.HasPrefix(value, "{") {
+		return "", fmt.Errorf("inline tables aren't supported")
+	}
+	if strings.HasPrefix(value, `"`) {
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+		return s, nil
+	}
+	if strings.HasPrefix(value, "'") {
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	}
+	return value, nil
+}
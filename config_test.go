@@ -0,0 +1,177 @@
+// This package tests the loading of the Resources from the environment and config files
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Service struct {
+	Postgres Postgres
+}
+
+type Postgres struct {
+	URL      string        `env:"DB_URL" config:"db.url,required"`
+	Password string        `env:"DB_PASSWORD,required,secret"`
+	Pool     int           `config:"db.pool" default:"4"`
+	Timeout  time.Duration `config:"db.timeout" default:"1s"`
+	Replicas []string      `config:"db.replicas"`
+	Ready    bool
+}
+
+func (d *Postgres) Init() *Postgres {
+	d.Ready = true
+	return d
+}
+
+func envOf(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func (p *Postgres) GET() *Postgres {
+	return p
+}
+
+func postgresOf(t *testing.T, rt Router) *Postgres {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/service/postgres", nil)
+	rt.ServeHTTP(w, req)
+
+	var response struct{ Postgres *Postgres }
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.Postgres
+}
+
+func TestConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"db": {"url": "postgres://json", "pool": 8, "replicas": ["a", "b"]}}`,
+		"config.toml": "# Overrides the json\n[db]\nurl = \"postgres://toml\" # inline comment\ntimeout = '3s'\n",
+		"config.yaml": "db:\n  replicas:\n    - c\n    - \"d\"\n",
+	}
+	paths := []string{}
+	for _, name := range []string{"config.json", "config.toml", "config.yaml"} {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(files[name]), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	rt, err := NewRouterWithConfig(Service{}, &Config{
+		Files:     paths,
+		LookupEnv: envOf(map[string]string{"DB_PASSWORD": "s3cr3t"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := postgresOf(t, rt)
+	expected := &Postgres{
+		URL:      "postgres://toml",
+		Password: "s3cr3t",
+		Pool:     8,
+		Timeout:  3 * time.Second,
+		Replicas: []string{"c", "d"},
+		Ready:    true,
+	}
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, db)
+	}
+}
+
+func TestConfigEnvOverridesDefaults(t *testing.T) {
+	rt, err := NewRouterWithConfig(Service{}, &Config{
+		LookupEnv: envOf(map[string]string{
+			"DB_URL":      "postgres://env",
+			"DB_PASSWORD": "s3cr3t",
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := postgresOf(t, rt)
+	if db.URL != "postgres://env" || db.Pool != 4 || db.Timeout != time.Second {
+		t.Errorf("Expected the env and the defaults, got %+v", db)
+	}
+}
+
+type Broken struct {
+	Pool  int  `env:"POOL,secret"`
+	Debug bool `env:"DEBUG"`
+}
+
+type Fleet struct {
+	Postgres Postgres
+	Broken   Broken
+}
+
+func TestConfigProblems(t *testing.T) {
+	_, err := NewRouterWithConfig(Fleet{}, &Config{
+		LookupEnv: envOf(map[string]string{"POOL": "many", "DEBUG": "maybe"}),
+	})
+
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Expected a *ConfigError, got %v", err)
+	}
+
+	expected := []string{
+		"/fleet/postgres: the field URL is required, set the env DB_URL or the config db.url",
+		"/fleet/postgres: the field Password is required, set the env DB_PASSWORD",
+		"/fleet/broken: invalid value '<redacted>' for the field Pool from env POOL",
+		"/fleet/broken: invalid value 'maybe' for the field Debug from env DEBUG",
+	}
+	if len(ce.Problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %q", len(expected), ce.Problems)
+	}
+	for i, problem := range ce.Problems {
+		if !strings.HasPrefix(problem, expected[i]) {
+			t.Errorf("Expected the problem %q, got %q", expected[i], problem)
+		}
+	}
+	if strings.Contains(err.Error(), "many") {
+		t.Errorf("The secret value was reported: %s", err)
+	}
+}
+
+func TestConfigUnsupportedSyntax(t *testing.T) {
+	cases := []struct {
+		parse func([]byte) (map[string][]string, error)
+		doc   string
+		line  string
+	}{
+		{parseTOMLConfig, "[db]\n[[db.replicas]]\nurl = 'a'\n", "line 2"},
+		{parseTOMLConfig, "replicas = [\n  'a',\n]\n", "line 1"},
+		{parseTOMLConfig, "db = { url = 'a' }\n", "line 1"},
+		{parseYAMLConfig, "db:\n  url: |\n    postgres://yaml\n", "line 2"},
+		{parseYAMLConfig, "db:\n  url: postgres://\n    yaml\n", "line 3"},
+		{parseYAMLConfig, "db: {url: a}\n", "line 1"},
+		{parseYAMLConfig, "db:\n  replicas:\n    - url: a\n", "line 3"},
+		{parseYAMLConfig, "db:\n  url: *default\n", "line 2"},
+		{parseYAMLConfig, "db:\n  pool: 1\n---\ndb:\n  pool: 2\n", "line 3"},
+	}
+
+	for _, c := range cases {
+		_, err := c.parse([]byte(c.doc))
+		if err == nil || !strings.HasPrefix(err.Error(), c.line+":") {
+			t.Errorf("Expected an error in the %s of %q, got %v", c.line, c.doc, err)
+		}
+	}
+}
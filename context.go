@@ -1,16 +1,43 @@
 package api
 
 import (
-	"log"
+	gocontext "context"
 	"net/http"
 	"reflect"
 )
 
 type context struct {
 	method *method
+	ctx    gocontext.Context // Derived from the request, carries its deadline
 	values []reflect.Value
 	idMap  idMap
 	errors []reflect.Value // To append the errors outputed
+
+	// The dependency whose constructor is running, used to report panics
+	constructing reflect.Type
+
+	// Dependencies constructed for this request, in construction order
+	// used to tear them down after the response has been written
+	constructed []reflect.Value
+
+	// Construct the dependencies concurrently before running the method
+	concurrent bool
+
+	// Values of the method plan, indexed by the slots of the plan
+	slots []reflect.Value
+
+	// The error of the failed constructor that stopped the request
+	failure error
+
+	// The list parameters of the request, if the method plan asks for them
+	list ListParams
+
+	// The links of the requested Resource and where they are answered
+	links     *Links
+	linkStyle LinkStyle
+
+	// Which ETags are answered
+	etags ETagMode
 }
 
 // Creates a new context
@@ -20,111 +47,85 @@ type context struct {
 func newContext(m *method, w http.ResponseWriter, req *http.Request, ids idMap) *context {
 	return &context{
 		method: m,
+		ctx:    req.Context(),
 		values: []reflect.Value{
 			reflect.ValueOf(w),
 			reflect.ValueOf(req),
 		},
-		idMap:  ids,
-		errors: []reflect.Value{},
+		idMap:       ids,
+		errors:      []reflect.Value{},
+		constructed: []reflect.Value{},
+		links:       m.plan.newLinks(),
 	}
 }
 
-func (c *context) run() []reflect.Value {
-
-	//log.Println("Running Context method Method:", c.method.Method.Method.Type)
-
-	// Then run the main method
-	inputs := c.getInputs(&c.method.method)
-
-	return c.method.method.Func.Call(inputs)
+// Return the request being answered
+func (c *context) request() *http.Request {
+	return c.values[requestSlot].Interface().(*http.Request)
 }
 
-// Return the inputs Values from a Method
-// For the especial case of the ID input, we should know the requester Type
-func (c *context) getInputs(m *reflect.Method) []reflect.Value {
-
-	requester := m.Type.In(0) // Get the requester Type
-
-	values := make([]reflect.Value, m.Type.NumIn())
-
-	//log.Println("Getting inputs:", inputs)
-	for i := 0; i < m.Type.NumIn(); i++ {
-		t := m.Type.In(i)
-
-		//log.Println("Getting input", t)
-		values[i] = c.valueOf(t, requester)
-		//log.Println("Getted", values[i], "for", t)
-
+// Creates a context out of any request
+// Used to construct the eager singletons when the Router is created
+func newStartupContext(m *method) *context {
+	return &context{
+		method: m,
+		ctx:    gocontext.Background(),
+		values: []reflect.Value{},
+		idMap:  idMap{},
+		errors: []reflect.Value{},
+		links:  m.plan.newLinks(),
 	}
-
-	//log.Println("Returning values:", values, "for", inputs)
-
-	return values
 }
 
-// Get the reflect.Value for the required type
-func (c *context) valueOf(t reflect.Type, requester reflect.Type) reflect.Value {
+// Run the mapped method constructing all its dependencies
+// It returns the context error if the request was cancelled
+// or its deadline exceeded before the method could be called,
+// or the error of the constructor that stopped the request
+func (c *context) run() ([]reflect.Value, error) {
 
-	//log.Println("Searching for", t)
+	//log.Println("Running Context method Method:", c.method.Method.Method.Type)
 
-	// If it is requesting the first error in the list
-	if t == errorType {
-		return c.errorValue()
+	p := c.method.plan
+	// The first slots are the ResponseWriter and the Request
+	c.slots = make([]reflect.Value, p.slots)
+	copy(c.slots, c.values)
+
+	// Invalid list parameters are answered before constructing anything
+	if p.list != nil {
+		var err error
+		c.list, err = parseListParams(c.request(), p.list)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// If it is requesting the whole error list
-	if t == errorSliceType {
-		return c.errorSliceValue()
+	// Construct all the dependencies following the method plan
+	if c.concurrent {
+		c.runStepsConcurrently()
+	} else {
+		for _, s := range p.steps {
+			c.runStep(s)
+			if c.failure != nil {
+				break
+			}
+		}
 	}
 
-	// If it is requesting the *ID type
-	if t == idInterfaceType {
-		return c.idValue(requester)
+	// Nobody can handle the failure, so don't call the method
+	if c.failure != nil {
+		return nil, c.failure
 	}
 
-	// So it can only be a Resource Value
-	// Or Request or Writer
-	v := c.resourceValue(t)
-
-	// If it is requiring the Elem itself and it returned a Ptr to Elem
-	// Or if it is requiring the Slice itself and it returned a Ptr to Slice
-	if t.Kind() == reflect.Struct && v.Kind() == reflect.Ptr ||
-		t.Kind() == reflect.Slice && v.Kind() == reflect.Ptr {
-		// It is requiring the Elem of a nil Ptr?
-		// Ok, give it an empty Elem of that Type
-		if v.IsNil() {
-			return reflect.New(t).Elem()
-		}
-
-		return v.Elem()
-		//log.Println("Transformed", v, "for", t)
+	// Dependencies stops being constructed when the context is done
+	// so the inputs could be incomplete, don't call the method
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	return v
-}
-
-// Get the Resource Value of the required Resource Type
-// It could be http.ResponseWriter or *http.Request too
-func (c *context) resourceValue(t reflect.Type) reflect.Value {
-	for _, v := range c.values {
-		switch t.Kind() {
-		case reflect.Interface:
-			if v.Type().Implements(t) {
-				return v
-			}
-		case reflect.Struct, reflect.Slice: // non-pointer
-			if v.Type().Elem() == t {
-				return v
-			}
-		case reflect.Ptr:
-			if v.Type() == t {
-				return v
-			}
-		}
+	// Then run the main method
+	inputs := c.planInputs(p.args)
 
-	}
-	// It is not present yet, so we need to construct it
-	return c.newDependencie(t)
+	return c.method.method.Func.Call(inputs), nil
 }
 
 // Return the first error of the list, or an nil error
@@ -157,72 +158,90 @@ func (c *context) idValue(t reflect.Type) reflect.Value {
 	return nilIDValue
 }
 
-// Construct all the dependencies level by level
-// Garants that every dependencie exists before be requisited
-func (c *context) newDependencie(t reflect.Type) reflect.Value {
+// Instanciate a new dependency and call its constructor, if it has one,
+// with the inputs returned by getInputs for the new value
+// It returns false if the constructor wasn't called or returned an error
+func (c *context) constructWith(dependencie *dependency, getInputs func(reflect.Value) []reflect.Value) (reflect.Value, bool) {
 
-	dependencie, exist := c.method.dependencies[t]
-	if !exist { // It should never occours
-		log.Printf("%v", c.method.dependencies)
-		log.Panicf("Dependencie %s not mapped!!!", t)
+	// Elements of collections are found by the ID in the URI
+	value, err := c.initialValue(dependencie)
+	if err != nil {
+		c.errors = append(c.errors, wrapConstructorError(dependencie, reflect.ValueOf(err)))
+		return dependencie.new(), false
 	}
 
-	//log.Println("Constructing dependency", dependencie.Value.Type())
-
-	// This Value will be mapped in the index index
-	index := len(c.values)
-
-	// Instanciate a new dependency and add it to the list
-	c.values = append(c.values, dependencie.new())
-
-	if dependencie.constructor != nil {
-
-		inputs := c.getInputs(dependencie.constructor) //dependencie.Input, dependencie.Value.Type())
-
-		out := make([]reflect.Value, dependencie.constructor.Type.NumOut())
-
-		//log.Printf("Calling %s with %q \n", dependencie.Method.Method.Type, inputs)
+	// Values without constructor are validated as they are
+	if dependencie.constructor == nil {
+		return value, c.validated(dependencie, value)
+	}
 
-		out = dependencie.constructor.Func.Call(inputs)
+	// If the request was cancelled or its deadline exceeded,
+	// there is no reason to keep constructing dependencies
+	if c.ctx.Err() != nil {
+		return value, false
+	}
 
-		// If the New method return something,
-		// it will be the resource itself with
-		// its values updated
-		if dependencie.constructor.Type.NumOut() > 0 {
+	inputs := getInputs(value)
 
-			for i := 0; i < dependencie.constructor.Type.NumOut(); i++ {
+	//log.Printf("Calling %s with %q \n", dependencie.Method.Method.Type, inputs)
 
-				out[i].Type()
+	c.constructing = dependencie.value.Type()
+	out := dependencie.constructor.Func.Call(inputs)
+	c.constructing = nil
 
-				//log.Println("### Threating output:", dependencie.Method.Outputs[i])
+	ok := true
 
-				if out[i].Type() == errorType {
-					//log.Println("### Fucking shit error!!!!", out[i].IsNil(), out[i].IsValid(), out[i].CanSet(), out[i].CanInterface())
-					if !out[i].IsNil() {
-						c.errors = append(c.errors, out[i])
-						//log.Println("### Appending the error!!!!")
-					}
-					continue
-				}
-				// Check if this output is the dependency itself
-				if dependencie.isType(out[i].Type()) {
-					//log.Println("### Its just me...", out[i].Type(), out[i].IsValid(), out[i].CanSet(), out[i].CanInterface(), out[i].Type())
+	// If the New method return something,
+	// it will be the resource itself with
+	// its values updated
+	for i := range out {
 
-					// If this method outputs an Elem insted an Ptr to the Elem
-					if out[i].Type().Kind() != reflect.Ptr {
-						value := reflect.New(out[i].Type())
-						value.Elem().Set(out[i])
-						c.values[index] = value
-					} else {
-						c.values[index] = out[i]
-					}
-				}
+		//log.Println("### Threating output:", dependencie.Method.Outputs[i])
 
+		if out[i].Type() == errorType {
+			if !out[i].IsNil() {
+				c.errors = append(c.errors, wrapConstructorError(dependencie, out[i]))
+				ok = false
+			}
+			continue
+		}
+		// Check if this output is the dependency itself
+		if dependencie.isType(out[i].Type()) {
+			// If this method outputs an Elem insted an Ptr to the Elem
+			if out[i].Type().Kind() != reflect.Ptr {
+				value = reflect.New(out[i].Type())
+				value.Elem().Set(out[i])
+			} else {
+				value = out[i]
 			}
+			continue
 		}
+		// Promoted constructors output the embedded Resource
+		if !dependencie.provided {
+			setPromotedOutput(value, out[i])
+		}
+	}
+
+	// The constructed value should satisfy the rules of its 'validate' tags
+	if ok {
+		ok = c.validated(dependencie, value)
 	}
 
-	//log.Println("Constructed", c.Values[index], "for", t, "value", c.Values[index].Interface())
+	//log.Println("Constructed", value, "value", value.Interface())
 
-	return c.values[index]
+	return value, ok
+}
+
+// Return true if the value satisfies the rules of its 'validate' tags
+// Otherwise the validation error is added to the context
+func (c *context) validated(dependencie *dependency, value reflect.Value) bool {
+	if dependencie.validator == nil {
+		return true
+	}
+	err := dependencie.validator.validate(value)
+	if err != nil {
+		c.errors = append(c.errors, wrapConstructorError(dependencie, reflect.ValueOf(err)))
+		return false
+	}
+	return true
 }
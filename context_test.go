@@ -0,0 +1,89 @@
+// This package tests the context.Context injection
+package api
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type Clock struct {
+	Slow Slow
+}
+
+// Returns the route template carried by the injected context
+func (c *Clock) GETRoute(ctx gocontext.Context) string {
+	return RouteFromContext(ctx).Template
+}
+
+type Slow struct {
+	Done bool
+}
+
+// Waits until the request context is done
+func (s *Slow) New(ctx gocontext.Context) *Slow {
+	<-ctx.Done()
+	return s
+}
+
+type Late struct{}
+
+// It should never be constructed, the context is done before
+func (l *Late) New(s *Slow) (*Late, error) {
+	panic("Late constructed after the context was done")
+}
+
+func (s *Slow) GET(_ *Late) *Slow {
+	s.Done = true
+	return s
+}
+
+func TestContextInjection(t *testing.T) {
+	rt, err := NewRouter(Clock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/clock/route", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+	errorTest(w, t)
+
+	var resp StringResp
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.String != "/clock/route" {
+		t.Fatal("Context not injected correctly")
+	}
+}
+
+func TestContextTimeout(t *testing.T) {
+	rt, err := NewRouter(Clock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.Child("slow").Timeout(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/clock/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, received %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
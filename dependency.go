@@ -11,6 +11,39 @@ type dependency struct {
 
 	// Constructor method New()
 	constructor *reflect.Method
+
+	// The lifetime of this dependency
+	scope Scope
+
+	// Singletons are constructed when the Router is created
+	eager bool
+
+	// Where the singleton value is stored
+	// It is shared by all dependencies of the same singleton
+	singleton *singleton
+
+	// The plan to construct the singleton and its dependencies
+	plan *plan
+
+	// What to do when its constructor fails
+	onError ErrorPolicy
+
+	// Rules the value should satisfy after its constructor runs
+	// It is nil if the value has no 'validate' tags
+	validator *validator
+
+	// True if it was replaced by a test double, that is never constructed
+	override bool
+
+	// True if it is constructed by a provider function
+	// The value is a pointer to the provided value
+	provided bool
+
+	// The initial state of each element, if it is the Elem of a collection
+	collection *collection
+
+	// Where the value is found, if it is the Elem of a slice backed by a Store
+	store Store
 }
 
 type dependencies map[reflect.Type]*dependency
@@ -29,14 +62,26 @@ func newDependencies(m reflect.Method, r *resource) (dependencies, error) {
 
 // Scan the dependencies of a Method
 func (ds dependencies) scanMethodInputs(m reflect.Method, r *resource) error {
-	//log.Println("Trying to scan method", m.Type)
+	return ds.scanInputs(m.Type, 0, r)
+}
+
+// Scan the dependencies of a function, starting from the input first
+func (ds dependencies) scanInputs(ft reflect.Type, first int, r *resource) error {
+	//log.Println("Trying to scan method", ft)
 	// So we scan all dependencies to create a tree
 
-	for i := 0; i < m.Type.NumIn(); i++ {
-		input := m.Type.In(i)
+	for i := first; i < ft.NumIn(); i++ {
+		input := ft.In(i)
 
 		//log.Println("Scanning for dependency", input, "on method", m.Type)
 
+		// An ambiguous Interface is reported even when
+		// some dependency already scanned satisfies it
+		err := r.checkAmbiguity(input)
+		if err != nil {
+			return err
+		}
+
 		// Check if this type already exists in the dependencies
 		// If it was indexed by another type, this method
 		// ensures that it will be indexed for this type too
@@ -65,9 +110,28 @@ func (ds dependencies) scanMethodInputs(m reflect.Method, r *resource) error {
 
 		ds.add(input, d)
 
+		// The dependencies of the test doubles aren't needed
+		if d.override {
+			continue
+		}
+
+		// Providers and qualified wrappers come with their constructor,
+		// its first input is where the value is stored
+		if d.constructor != nil {
+			err = ds.scanInputs(d.constructor.Type, 1, r)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Check if Dependency constructor exists
-		constructor, exists := d.value.Type().MethodByName("New")
-		if !exists {
+		// The constructors of its embedded Resources are composed if Go didn't promote them
+		constructor, err := methodOrComposition(d.value.Type(), "New", isValidConstructor)
+		if err != nil {
+			return err
+		}
+		if constructor == nil {
 			//log.Printf("Type %s doesn't have New method\n", d.Value.Type())
 			continue
 		}
@@ -76,20 +140,20 @@ func (ds dependencies) scanMethodInputs(m reflect.Method, r *resource) error {
 
 		// 'New' method should have no return,
 		// or return just the resource itself and/or error
-		err = isValidConstructor(constructor)
+		err = isValidConstructor(*constructor)
 		if err != nil {
 			return err
 		}
 
 		//log.Println("Scan Dependencies for 'New' method", d.Method.Method.Type)
 
-		err = ds.scanMethodInputs(constructor, r)
+		err = ds.scanMethodInputs(*constructor, r)
 		if err != nil {
 			return err
 		}
 
 		// And attach it into the Dependency Method
-		d.constructor = &constructor
+		d.constructor = constructor
 	}
 	return nil
 }
@@ -101,6 +165,21 @@ func newDependency(t reflect.Type, r *resource) (*dependency, error) {
 
 	//log.Println("Trying to create a new dependency", t)
 
+	// Test doubles are shared by all requests, as singletons already built
+	if s, exist := r.root().overrides[overrideKey(t)]; exist {
+		return &dependency{
+			value:     s.value,
+			scope:     SingletonScope,
+			singleton: s,
+			override:  true,
+		}, nil
+	}
+
+	// Providers can construct any type, they have priority over the Resource tree
+	if p, exist := r.root().providers[t]; exist {
+		return newProvidedDependency(p, r)
+	}
+
 	err := isValidDependencyType(t)
 	if err != nil {
 		return nil, err
@@ -110,14 +189,54 @@ func newDependency(t reflect.Type, r *resource) (*dependency, error) {
 	// we should search which resource satisfies this Interface in the Resource Tree
 	// If this is a Struct, just find for the initial value,
 	// if the Struct doesn't exist, create one and return it
-	v, err := r.valueOf(t)
+	res, err := r.resourceOf(t)
 	if err != nil {
 		return nil, err
 	}
 
+	var v reflect.Value
+	if res != nil {
+		v = res.value
+	} else {
+		v, err = newEmptyValue(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	d := &dependency{
 		value:       v,
 		constructor: nil,
+		scope:       RequestScope,
+	}
+
+	if res != nil && res.parent != nil && res.parent.store != nil {
+		d.store = res.parent.store
+	} else if res != nil && res.isElement() {
+		d.collection = res.parent.collection
+	}
+
+	// Wrappers out of the tree are filled with the qualified Resources
+	if res == nil && isQualifiedWrapper(t) {
+		d.constructor, err = newWrapperConstructor(t, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = d.setScope(res, r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.setErrorPolicy(res)
+	if err != nil {
+		return nil, err
+	}
+
+	d.validator, err = validatorOf(v.Type())
+	if err != nil {
+		return nil, err
 	}
 
 	//log.Printf("Created dependency %s to use as %s\n", v, t)
@@ -175,6 +294,11 @@ func (ds dependencies) exists(t reflect.Type) bool {
 // Return true if this Resrouce is from by this Type
 func (d *dependency) isType(t reflect.Type) bool {
 
+	// Provided values are matched by the exact type
+	if d.provided {
+		return d.value.Type().Elem() == t
+	}
+
 	if t.Kind() == reflect.Interface {
 		return d.value.Type().Implements(t)
 	}
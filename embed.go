@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//
+// Embedded (anonymous) Resources are composed into the Resource that embeds them
+// Their children are added to it, and their mapped methods, Init and New
+// are promoted to it by Go itself
+// When more than one embedded Resource has an Init or New method,
+// Go promotes none of them, so they are composed and called in the fields order
+// The methods declared by the embedding Resource always win
+//
+
+// A method of an embedded field, at the index of the embedding Struct
+type embeddedMethod struct {
+	index  []int
+	method reflect.Method
+}
+
+// Return the methods with this name of the embedded Structs of the type,
+// in the fields order
+// Embedded Structs that don't have it are searched recursively
+func embeddedMethods(t reflect.Type, name string) []embeddedMethod {
+	t = elemOfType(t)
+	found := []embeddedMethod{}
+	if t.Kind() != reflect.Struct {
+		return found
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		// Embedded Ptrs could be nil, so just Structs are composed
+		if !field.Anonymous || field.Type.Kind() != reflect.Struct || field.PkgPath != "" {
+			continue
+		}
+
+		m, exist := reflect.PtrTo(field.Type).MethodByName(name)
+		if exist {
+			found = append(found, embeddedMethod{index: field.Index, method: m})
+			continue
+		}
+
+		for _, em := range embeddedMethods(field.Type, name) {
+			em.index = append([]int{i}, em.index...)
+			found = append(found, em)
+		}
+	}
+
+	return found
+}
+
+// Return the method with this name of the type, or the composition
+// of the methods of its embedded Structs if Go didn't promote any of them
+// Each embedded method is checked by the validate function
+func methodOrComposition(t reflect.Type, name string, validate func(reflect.Method) error) (*reflect.Method, error) {
+	m, exist := t.MethodByName(name)
+	if exist {
+		return &m, nil
+	}
+
+	if isSliceType(t) {
+		return nil, nil
+	}
+
+	embedded := embeddedMethods(t, name)
+	if len(embedded) == 0 {
+		return nil, nil
+	}
+
+	for _, em := range embedded {
+		err := validate(em.method)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return composeMethods(t, name, embedded), nil
+}
+
+// Creates a method that calls the embedded methods on their fields, in order
+// Its inputs are all the inputs the embedded methods receive, but the receivers,
+// and it outputs the first error they output
+// The outputs of the embedded types are stored in their fields
+func composeMethods(t reflect.Type, name string, embedded []embeddedMethod) *reflect.Method {
+
+	in := []reflect.Type{t}
+	position := map[reflect.Type]int{}
+	fails := false
+
+	for _, em := range embedded {
+		for i := 1; i < em.method.Type.NumIn(); i++ {
+			input := em.method.Type.In(i)
+			if _, exist := position[input]; !exist {
+				position[input] = len(in)
+				in = append(in, input)
+			}
+		}
+		for i := 0; i < em.method.Type.NumOut(); i++ {
+			fails = fails || em.method.Type.Out(i) == errorType
+		}
+	}
+
+	out := []reflect.Type{}
+	if fails {
+		out = append(out, errorType)
+	}
+
+	composedType := reflect.FuncOf(in, out, false)
+	composed := reflect.MakeFunc(composedType, func(args []reflect.Value) []reflect.Value {
+		for _, em := range embedded {
+			field := args[0].Elem().FieldByIndex(em.index)
+
+			inputs := make([]reflect.Value, em.method.Type.NumIn())
+			inputs[0] = field
+			if em.method.Type.In(0).Kind() == reflect.Ptr {
+				inputs[0] = field.Addr()
+			}
+			for i := 1; i < len(inputs); i++ {
+				inputs[i] = args[position[em.method.Type.In(i)]]
+			}
+
+			for _, o := range em.method.Func.Call(inputs) {
+				if o.Type() == errorType {
+					if !o.IsNil() {
+						return []reflect.Value{o}
+					}
+					continue
+				}
+				setEmbeddedOutput(field, o)
+			}
+		}
+
+		if fails {
+			return []reflect.Value{reflect.Zero(errorType)}
+		}
+		return nil
+	})
+
+	return &reflect.Method{
+		Name: name + "Embedded",
+		Type: composedType,
+		Func: composed,
+	}
+}
+
+// Return the index of the embedded field of the type t inside the Struct owner
+func embeddedIndex(owner reflect.Type, t reflect.Type) ([]int, bool) {
+	owner = elemOfType(owner)
+	t = elemOfType(t)
+	if owner.Kind() != reflect.Struct || t.Name() == "" {
+		return nil, false
+	}
+
+	field, exist := owner.FieldByName(t.Name())
+	if !exist || !field.Anonymous || field.Type != t {
+		return nil, false
+	}
+	return field.Index, true
+}
+
+// Store the output of an embedded method in its field
+// Nil Ptrs are ignored, like the Init and New outputs of the Resource itself
+func setEmbeddedOutput(field reflect.Value, out reflect.Value) {
+	if out.Kind() == reflect.Ptr {
+		if out.IsNil() {
+			return
+		}
+		out = out.Elem()
+	}
+	field.Set(out)
+}
+
+// Store the output of a promoted Init or New method in the embedded field of the value
+// Return false if the output isn't from an embedded type
+func setPromotedOutput(value reflect.Value, out reflect.Value) bool {
+	index, exist := embeddedIndex(value.Type(), out.Type())
+	if !exist {
+		return false
+	}
+	setEmbeddedOutput(value.Elem().FieldByIndex(index), out)
+	return true
+}
+
+// Return an error if two embedded Resources map the same method and the embedding
+// Resource doesn't declare it, so Go didn't promote any of them
+func checkEmbeddedConflicts(r *resource) error {
+	declaredBy := map[string]*resource{}
+
+	for _, extend := range r.extends {
+		t := extend.value.Type()
+		for i := 0; i < t.NumMethod(); i++ {
+			m := t.Method(i)
+			if !isMappedMethod(m) {
+				continue
+			}
+			if _, promoted := r.value.Type().MethodByName(m.Name); promoted {
+				continue
+			}
+			if other, exist := declaredBy[m.Name]; exist {
+				return fmt.Errorf("The method %s is mapped by both embedded Resources %s and %s of %s, "+
+					"declare it in the embedding Resource to choose one",
+					m.Name, other.value.Type(), t, strings.TrimSpace(r.String()))
+			}
+			declaredBy[m.Name] = extend
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+// This package tests the composition of embedded Resources
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type Auditable struct {
+	Created string
+	Editor  string
+	History History
+}
+
+func (a *Auditable) Init() *Auditable {
+	a.Created = "today"
+	return a
+}
+
+func (a *Auditable) New(req *http.Request) {
+	a.Editor = req.Header.Get("Editor")
+}
+
+func (a *Auditable) GETAudit() string {
+	return a.Created + " by " + a.Editor
+}
+
+type History struct{}
+
+func (h *History) GET() string {
+	return "inherited history"
+}
+
+type Paginated struct {
+	PageSize int
+	Page     string
+}
+
+func (p *Paginated) Init() {
+	p.PageSize = 20
+}
+
+func (p *Paginated) New(req *http.Request) *Paginated {
+	p.Page = req.URL.Query().Get("page")
+	return p
+}
+
+type Journal struct {
+	Article Article
+	Memo    Memo
+}
+
+type Article struct {
+	Auditable
+	Paginated
+	Title string
+}
+
+func (a *Article) GET() *Article {
+	return a
+}
+
+type Memo struct {
+	Auditable
+	History OwnHistory
+}
+
+func (m *Memo) Init() {
+	m.Created = "yesterday"
+}
+
+type OwnHistory struct{}
+
+func (h *OwnHistory) GET() string {
+	return "own history"
+}
+
+func getJournal(t *testing.T, rt Router, path string, v interface{}) {
+	decodeBody(t, doRequest(rt, "GET", path, "", "Editor", "gopher"), v)
+}
+
+func TestEmbeddedResources(t *testing.T) {
+	rt, err := NewRouter(Journal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Inits and constructors of both embedded Resources are composed
+	var article struct{ Article Article }
+	getJournal(t, rt, "/journal/article?page=3", &article)
+	a := article.Article
+	if a.Created != "today" || a.Editor != "gopher" || a.PageSize != 20 || a.Page != "3" {
+		t.Errorf("Expected the embedded Resources initialized and constructed, got %+v", a)
+	}
+
+	// The actions and children are inherited
+	var resp StringResp
+	getJournal(t, rt, "/journal/article/audit", &resp)
+	if resp.String != "today by gopher" {
+		t.Errorf("Expected the inherited action, got %q", resp.String)
+	}
+	getJournal(t, rt, "/journal/article/history", &resp)
+	if resp.String != "inherited history" {
+		t.Errorf("Expected the inherited child, got %q", resp.String)
+	}
+
+	// The Init and children declared by the embedding Resource win
+	getJournal(t, rt, "/journal/memo/audit", &resp)
+	if resp.String != "yesterday by gopher" {
+		t.Errorf("Expected the declared Init to override the embedded one, got %q", resp.String)
+	}
+	getJournal(t, rt, "/journal/memo/history", &resp)
+	if resp.String != "own history" {
+		t.Errorf("Expected the declared child to override the inherited one, got %q", resp.String)
+	}
+}
+
+type Tracked struct {
+	History History
+}
+
+func (t *Tracked) GETAudit() string {
+	return "tracked"
+}
+
+type Clash struct {
+	Auditable
+	Tracked
+}
+
+type Chronicle struct {
+	Clash Clash
+}
+
+func TestEmbeddedConflicts(t *testing.T) {
+	_, err := NewRouter(Chronicle{})
+	if err == nil || !strings.Contains(err.Error(), "history") {
+		t.Errorf("Expected the inherited children conflict, got %v", err)
+	}
+
+	type Quiet struct {
+		Auditable
+		Tracked
+		History OwnHistory
+	}
+	type Archive struct {
+		Quiet Quiet
+	}
+	_, err = NewRouter(Archive{})
+	if err == nil || !strings.Contains(err.Error(), "GETAudit") {
+		t.Errorf("Expected the embedded actions conflict, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Wraps the error returned by a constructor
+// with the type of the dependency that produced it
+// Its message is just the message of the original error,
+// use errors.As to get the original error or the type
+type DependencyError struct {
+	Type reflect.Type
+	Err  error
+}
+
+func (e *DependencyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// Return true if this Type is an specific error type, like *ValidationError,
+// that can be asked by the methods insted of the error interface
+func isTypedErrorType(t reflect.Type) bool {
+	return t != errorType && t.Implements(errorType)
+}
+
+// Return the error Value outputed by the constructor of the dependency,
+// wrapped with the dependency type
+func wrapConstructorError(d *dependency, out reflect.Value) reflect.Value {
+	t := d.value.Type()
+	if d.provided {
+		t = t.Elem()
+	}
+
+	var err error = &DependencyError{
+		Type: t,
+		Err:  out.Interface().(error),
+	}
+	return reflect.ValueOf(&err).Elem()
+}
+
+// Return the first error of the list that matches the type t, or a nil Value of type t
+// The errors are matched with errors.As, so wrapped errors are found too
+func (c *context) typedErrorValue(t reflect.Type) reflect.Value {
+	target := reflect.New(t)
+	for _, err := range c.errors {
+		if errors.As(err.Interface().(error), target.Interface()) {
+			return target.Elem()
+		}
+	}
+	return reflect.Zero(t)
+}
+
+// Return true if the error matches some of the error types
+func matchesSomeType(err error, types []reflect.Type) bool {
+	for _, t := range types {
+		if errors.As(err, reflect.New(t).Interface()) {
+			return true
+		}
+	}
+	return false
+}
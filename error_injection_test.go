@@ -0,0 +1,99 @@
+// This package tests the injection of specific error types
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type Signup struct {
+	Form  Form
+	Email Email
+}
+
+type ValidationError struct {
+	Field string
+}
+
+func (e *ValidationError) Error() string {
+	return "Invalid field " + e.Field
+}
+
+type NotFoundError struct{}
+
+func (e *NotFoundError) Error() string   { return "Not found" }
+func (e *NotFoundError) StatusCode() int { return http.StatusNotFound }
+
+type Form struct{}
+
+func (f *Form) New() error {
+	return &ValidationError{Field: "name"}
+}
+
+func (f *Form) POST(verr *ValidationError, nf *NotFoundError) string {
+	if verr == nil || nf != nil {
+		return "wrong errors injected"
+	}
+	return verr.Field
+}
+
+func (f *Form) POSTWrapped(err error) string {
+	var derr *DependencyError
+	if !errors.As(err, &derr) || derr.Type != reflect.TypeOf(&Form{}) {
+		return "not wrapped"
+	}
+	return err.Error()
+}
+
+type Email struct{}
+
+func (e *Email) New() error {
+	return &NotFoundError{}
+}
+
+func (e *Email) POST(verr *ValidationError) string {
+	return "called"
+}
+
+func TestTypedErrorInjection(t *testing.T) {
+	rt, err := NewRouter(Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		uri      string
+		code     int
+		response string
+	}{
+		{"/signup/form", http.StatusOK, "name"},
+		{"/signup/form/wrapped", http.StatusOK, "Invalid field name"},
+		{"/signup/email", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", c.uri, nil)
+		rt.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Fatalf("Expected %d for %s, got %d: %s", c.code, c.uri, w.Code, w.Body)
+		}
+		if c.code != http.StatusOK {
+			continue
+		}
+
+		var resp StringResp
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.String != c.response {
+			t.Errorf("Expected %q for %s, got %q", c.response, c.uri, resp.String)
+		}
+	}
+}
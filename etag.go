@@ -0,0 +1,173 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//
+// Successful GET and HEAD responses can be validated by the clients
+// With the ETags enabled, the ETag header is the hash of the encoded response,
+// or the tag returned by the output implementing api.ETagger,
+// and the requests with a matching If-None-Match are answered as 304
+// Resources implementing api.LastModifier have the Last-Modified header,
+// and the requests with an If-Modified-Since not older than it are answered as 304
+//
+
+// Which ETags are answered
+type ETagMode int
+
+const (
+	// The ETags aren't answered
+	// This is the default mode
+	NoETags ETagMode = iota
+
+	// The ETags are strong validators, the responses are byte for byte the same
+	StrongETags
+
+	// The ETags are weak validators, the responses are semantically the same
+	WeakETags
+)
+
+// Outputs can implement this interface to define their own ETag
+// instead of the hash of the encoded response
+type ETagger interface {
+	ETag() string
+}
+
+// Resources and outputs can implement this interface
+// to answer the Last-Modified header
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+var (
+	etaggerInterfaceType      = reflect.TypeOf((*ETagger)(nil)).Elem()
+	lastModifierInterfaceType = reflect.TypeOf((*LastModifier)(nil)).Elem()
+)
+
+// Define which ETags are answered by this Route and its children
+// The mode defined in a child Route overrides the one defined in its parents
+func (ro *route) ETags(mode ETagMode) {
+	ro.etags = mode
+	ro.etagsDefined = true
+}
+
+// Write the validators of the response and return true if the request
+// is conditional and the client already has this response
+// In that case the response is answered as 304, without body
+// The If-Modified-Since is ignored when the request has an If-None-Match
+func (c *context) notModified(w http.ResponseWriter, output []reflect.Value, body []byte) bool {
+	req := c.request()
+
+	// Just the successful responses with some content are validated
+	if req.Method != "GET" && req.Method != "HEAD" || body == nil || outcomeOf(output) != nil {
+		return false
+	}
+
+	if c.etags != NoETags {
+		tag := etagOf(output, body, c.etags)
+		w.Header().Set("ETag", tag)
+
+		if match := req.Header.Get("If-None-Match"); match != "" {
+			if !etagMatches(match, tag) {
+				return false
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	modified, ok := c.lastModified(output)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+
+	if req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || modified.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// Return the ETag of the response, quoted and marked as weak if it should be
+func etagOf(output []reflect.Value, body []byte, mode ETagMode) string {
+	tag := ""
+	for _, v := range output {
+		if tagger, ok := interfaceOf(v, etaggerInterfaceType); ok {
+			tag = tagger.(ETagger).ETag()
+			break
+		}
+	}
+
+	if tag == "" {
+		sum := sha256.Sum256(body)
+		tag = hex.EncodeToString(sum[:16])
+	}
+
+	tag = strings.TrimPrefix(tag, "W/")
+	if !strings.HasPrefix(tag, "\"") {
+		tag = "\"" + tag + "\""
+	}
+	if mode == WeakETags {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// Return true if some of the ETags in the If-None-Match matches the tag
+// They are compared with the weak comparison, ignoring the W/ prefix
+func etagMatches(match string, tag string) bool {
+	if strings.TrimSpace(match) == "*" {
+		return true
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(match, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the last modification of the outputs, or of the Resource receiving the method
+func (c *context) lastModified(output []reflect.Value) (time.Time, bool) {
+	values := output
+	if p := c.method.plan; len(p.args) > 0 && p.args[0].kind == slotArgument {
+		values = append(append([]reflect.Value{}, output...), c.argumentValue(&p.args[0]))
+	}
+
+	for _, v := range values {
+		if modifier, ok := interfaceOf(v, lastModifierInterfaceType); ok {
+			modified := modifier.(LastModifier).LastModified()
+			if !modified.IsZero() {
+				return modified, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Return the value as the interface type, if it or its Ptr implements it
+func interfaceOf(v reflect.Value, t reflect.Type) (interface{}, bool) {
+	if !v.IsValid() || !v.CanInterface() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, false
+	}
+	if v.Type().Implements(t) {
+		return v.Interface(), true
+	}
+	if v.Kind() != reflect.Ptr && ptrOfType(v.Type()).Implements(t) {
+		return ptrOfValue(v).Interface(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+// This package tests the ETags and the conditional requests
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Newsroom struct {
+	Headline Headline
+	Forecast Forecast
+}
+
+type Headline struct {
+	Title string
+}
+
+func (h *Headline) GET() *Headline {
+	return h
+}
+
+func (h *Headline) LastModified() time.Time {
+	return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+}
+
+type Forecast struct {
+	Sky string
+}
+
+func (f Forecast) ETag() string {
+	return "sky-" + f.Sky
+}
+
+func (f *Forecast) GET() Forecast {
+	return *f
+}
+
+func TestETags(t *testing.T) {
+	rt, err := NewRouter(Newsroom{Headline: Headline{Title: "Gophers"}, Forecast: Forecast{Sky: "blue"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabled by default
+	w := doRequest(rt, "GET", "/newsroom/headline", "")
+	if w.Header().Get("ETag") != "" {
+		t.Fatalf("Expected no ETag by default, got %s", w.Header().Get("ETag"))
+	}
+
+	rt.ETags(StrongETags)
+
+	w = doRequest(rt, "GET", "/newsroom/headline", "")
+	tag := w.Header().Get("ETag")
+	if !strings.HasPrefix(tag, "\"") || w.Code != http.StatusOK {
+		t.Fatalf("Expected a strong ETag, got %d %q", w.Code, tag)
+	}
+
+	// The same response has the same ETag
+	w = doRequest(rt, "GET", "/newsroom/headline", "", "If-None-Match", `"other", `+tag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected 304 without body, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(rt, "GET", "/newsroom/headline", "", "If-None-Match", `"other"`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for another ETag, got %d", w.Code)
+	}
+
+	// The outputs can define their ETags
+	rt.Child("forecast").ETags(WeakETags)
+	w = doRequest(rt, "GET", "/newsroom/forecast", "")
+	if w.Header().Get("ETag") != `W/"sky-blue"` {
+		t.Errorf("Expected the weak ETag of the output, got %q", w.Header().Get("ETag"))
+	}
+	w = doRequest(rt, "GET", "/newsroom/forecast", "", "If-None-Match", `"sky-blue"`)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 with the weak comparison, got %d", w.Code)
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	rt, err := NewRouter(Newsroom{Headline: Headline{Title: "Gophers"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := doRequest(rt, "GET", "/newsroom/headline", "")
+	if w.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Fatalf("Expected the Last-Modified, got %q", w.Header().Get("Last-Modified"))
+	}
+
+	cases := map[string]int{
+		"Wed, 01 May 2024 12:00:00 GMT": http.StatusNotModified,
+		"Thu, 02 May 2024 12:00:00 GMT": http.StatusNotModified,
+		"Tue, 30 Apr 2024 12:00:00 GMT": http.StatusOK,
+	}
+	for since, status := range cases {
+		w := doRequest(rt, "GET", "/newsroom/headline", "", "If-Modified-Since", since)
+		if w.Code != status {
+			t.Errorf("Expected %d for If-Modified-Since %s, got %d", status, since, w.Code)
+		}
+	}
+}
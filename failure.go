@@ -0,0 +1,161 @@
+package api
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// What to do when the constructor of a dependency fails
+type ErrorPolicy int
+
+const (
+	// Stop constructing the dependencies and answer the request with the error,
+	// unless some constructor or the mapped method asks for the error or []error
+	// after the failed dependency, so it can handle the error by itself
+	// This is the default policy
+	StopOnError ErrorPolicy = iota
+
+	// Keep constructing the dependencies and call the mapped method anyway
+	ContinueOnError
+)
+
+// Resources can implement this interface to define their error policy
+// The 'onerror' tag in the Resource field overrides it
+type ErrorPolicer interface {
+	ErrorPolicy() ErrorPolicy
+}
+
+// Errors can implement this interface to define
+// the Status Code sent when they stop the request
+// Other errors are answered as 500
+type StatusCoder interface {
+	StatusCode() int
+}
+
+var errorPolicerInterfaceType = reflect.TypeOf((*ErrorPolicer)(nil)).Elem()
+
+func (p ErrorPolicy) String() string {
+	if p == ContinueOnError {
+		return "continue"
+	}
+	return "stop"
+}
+
+// Parse the 'onerror' tag of a Resource field
+// Ex: `onerror:"stop"` or `onerror:"continue"`
+func parseErrorPolicy(tag reflect.StructTag) (policy ErrorPolicy, defined bool, err error) {
+	value, defined := tag.Lookup("onerror")
+	if !defined {
+		return StopOnError, false, nil
+	}
+
+	switch value {
+	case "stop":
+		return StopOnError, true, nil
+	case "continue":
+		return ContinueOnError, true, nil
+	}
+
+	return StopOnError, true, fmt.Errorf("Invalid error policy '%s' in the tag %s", value, tag)
+}
+
+// Define the error policy of the dependency
+// The tag of the Resource field has priority over the ErrorPolicer interface
+func (d *dependency) setErrorPolicy(res *resource) error {
+
+	if res != nil {
+		policy, defined, err := parseErrorPolicy(res.tag)
+		if err != nil {
+			return err
+		}
+		if defined {
+			d.onError = policy
+			return nil
+		}
+	}
+
+	if d.value.Type().Implements(errorPolicerInterfaceType) {
+		d.onError = d.value.Interface().(ErrorPolicer).ErrorPolicy()
+	}
+
+	return nil
+}
+
+// Return the Status Code that answers a request stopped by the error
+func errorStatus(err error) int {
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+	if errors.Is(err, gocontext.DeadlineExceeded) || errors.Is(err, gocontext.Canceled) {
+		return contextErrorStatus(err)
+	}
+	return http.StatusInternalServerError
+}
+
+// Decide which steps of the plan stop the request when their constructor fails
+// The failure can be handled just by the ones that runs after it
+// and asks for the error or []error
+// Specific error types handle just the failures that match them
+func (pl *planner) stops() {
+	handled := asksForErrors(pl.plan.args)
+	handles := typedErrors(pl.plan.args, nil)
+	for i := len(pl.plan.steps) - 1; i >= 0; i-- {
+		s := pl.plan.steps[i]
+		s.stop = !handled && s.dependency.onError == StopOnError && s.dependency.canFail()
+		if s.stop {
+			s.handles = handles
+		}
+		handled = handled || asksForErrors(s.args)
+		handles = typedErrors(s.args, handles)
+	}
+}
+
+// Return true if the dependency constructor outputs an error
+// or the constructed value is validated
+// Elements of collections and Stores fail when their ID doesn't exist
+func (d *dependency) canFail() bool {
+	if d.collection != nil || d.store != nil || d.validator != nil {
+		return true
+	}
+	// Singletons aren't constructed when some of their dependencies fail
+	if d.plan != nil {
+		for _, s := range d.plan.steps {
+			if s.stop {
+				return true
+			}
+		}
+	}
+	if d.constructor == nil {
+		return false
+	}
+	for i := 0; i < d.constructor.Type.NumOut(); i++ {
+		if d.constructor.Type.Out(i) == errorType {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if some of the arguments is the error or []error
+func asksForErrors(args []argument) bool {
+	for _, a := range args {
+		if a.kind == errorArgument || a.kind == errorSliceArgument {
+			return true
+		}
+	}
+	return false
+}
+
+// Append the specific error types asked by the arguments to the list
+func typedErrors(args []argument, types []reflect.Type) []reflect.Type {
+	for _, a := range args {
+		if a.kind == typedErrorArgument {
+			types = append(types[:len(types):len(types)], a.t)
+		}
+	}
+	return types
+}
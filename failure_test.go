@@ -0,0 +1,143 @@
+// This package tests the request short-circuit when a constructor fails
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var called bool
+
+type Vault struct {
+	Account Account
+	Ledger  Ledger
+	Legacy  Legacy `onerror:"continue"`
+	Lenient Lenient
+	Report  Report
+}
+
+type notFound struct{}
+
+func (e notFound) Error() string   { return "Account not found" }
+func (e notFound) StatusCode() int { return http.StatusNotFound }
+
+type Account struct{}
+
+func (a *Account) New() error {
+	return notFound{}
+}
+
+func (a *Account) GET() string {
+	called = true
+	return "garbage"
+}
+
+func (a *Account) GETChecked(err error) (string, error) {
+	called = true
+	return "checked", err
+}
+
+type Ledger struct{}
+
+func (l *Ledger) New() error {
+	return errors.New("Ledger unavailable")
+}
+
+func (l *Ledger) GET() string {
+	called = true
+	return "garbage"
+}
+
+type Legacy struct{}
+
+func (l *Legacy) New() error {
+	return errors.New("Legacy failed")
+}
+
+func (l *Legacy) GET() string {
+	called = true
+	return "legacy"
+}
+
+type Lenient struct{}
+
+func (l Lenient) ErrorPolicy() ErrorPolicy {
+	return ContinueOnError
+}
+
+func (l *Lenient) New() error {
+	return errors.New("Lenient failed")
+}
+
+func (l *Lenient) GET() string {
+	called = true
+	return "lenient"
+}
+
+// Report handles the Account failure in its constructor
+type Report struct {
+	Missing bool
+}
+
+func (r *Report) New(a *Account, err error) *Report {
+	r.Missing = err != nil
+	return r
+}
+
+func (r *Report) GET() *Report {
+	called = true
+	return r
+}
+
+func TestShortCircuit(t *testing.T) {
+	for _, concurrent := range []bool{false, true} {
+		rt, err := NewRouter(Vault{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rt.ConstructConcurrently(concurrent)
+
+		cases := []struct {
+			uri    string
+			code   int
+			called bool
+		}{
+			{"/vault/account", http.StatusNotFound, false},
+			{"/vault/account/checked", http.StatusOK, true},
+			{"/vault/ledger", http.StatusInternalServerError, false},
+			{"/vault/legacy", http.StatusOK, true},
+			{"/vault/lenient", http.StatusOK, true},
+			{"/vault/report", http.StatusOK, true},
+		}
+
+		for _, c := range cases {
+			called = false
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", c.uri, nil)
+			rt.ServeHTTP(w, req)
+
+			if w.Code != c.code {
+				t.Errorf("Concurrent %t: expected %d for %s, got %d: %s",
+					concurrent, c.code, c.uri, w.Code, w.Body)
+			}
+			if called != c.called {
+				t.Errorf("Concurrent %t: method of %s called: %t, expected %t",
+					concurrent, c.uri, called, c.called)
+			}
+		}
+	}
+}
+
+func TestInvalidErrorPolicy(t *testing.T) {
+	type Broken struct {
+		Legacy Legacy `onerror:"ignore"`
+	}
+
+	_, err := NewRouter(Broken{})
+	if err == nil {
+		t.Fatal("Expected an error for the invalid 'onerror' tag")
+	}
+}
@@ -0,0 +1,933 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Writes the Go source of a Router generated for the Resource tree of the object
+// The generated Router answers the requests calling the constructors and the
+// mapped methods directly, without reflection, and produces the same responses
+// the Router returned by NewRouter produces
+// pkg is the name of the package that declares the Resources,
+// the generated file should be saved in this package
+// It receives the Field name and Field tag as optional arguments, like NewRouter
+//
+// The Init methods aren't called when generating, the generated Router calls them
+// Middlewares, timeouts, panics recovery, singletons, shutdown, health endpoints,
+// the configuration loading, the providers, the collections, the Stores,
+// the api.ListParams, the hypermedia links, the conditional requests
+// and the cache policies aren't supported
+// It returns an error when the Resource tree uses some of them
+func Generate(w io.Writer, pkg string, object interface{}, args ...string) error {
+
+	value := reflect.ValueOf(object)
+
+	r, err := newResource(value, rootField(value, args), nil, false, nil)
+	if err != nil {
+		return err
+	}
+
+	ro, err := newRoute(r)
+	if err != nil {
+		return err
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		pkgPath: elemOfType(value.Type()).PkgPath(),
+		apiPath: reflect.TypeOf(id{}).PkgPath(),
+		imports: map[string]string{},
+		root:    r,
+		route:   ro,
+		object:  value.Type(),
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+//
+// Helpers used by the generated Routers
+// They are exported just to be called by the generated code,
+// they aren't meant to be used directly and can change with the generator
+//
+
+// The ID injected when the ID isn't present in the URI
+// For internal use of the generated Routers
+var NilID ID = (*id)(nil)
+
+// Creates the ID caught in the URI
+// For internal use of the generated Routers
+func NewID(s string) ID {
+	return &id{id: s}
+}
+
+// Write the outputs of a mapped method in the ResponseWriter encoded as JSON
+// It receives the names of the outputs and a pointer to each output
+// It returns the outcome of the request, used to tear down the dependencies
+// For internal use of the generated Routers
+func WriteOutput(w http.ResponseWriter, names []string, outputs ...interface{}) error {
+	values := make([]reflect.Value, len(outputs))
+	for i, o := range outputs {
+		values[i] = reflect.ValueOf(o).Elem()
+	}
+	return writeOutput(w, names, values)
+}
+
+// Write an error and Status Code in the ResponseWriter encoded as JSON
+// For internal use of the generated Routers
+func WriteError(w http.ResponseWriter, err error, status int) {
+	writeError(w, err, status)
+}
+
+// Return the Status Code that answers a request stopped by the error
+// It could be a constructor error or the context error
+// For internal use of the generated Routers
+func ErrorStatus(err error) int {
+	return errorStatus(err)
+}
+
+// Store the Route metadata in the request context
+// For internal use of the generated Routers
+func WithRoute(req *http.Request, info *RouteInfo) *http.Request {
+	return withRouteInfo(req, info)
+}
+
+//
+// The generator
+//
+
+type generator struct {
+	pkg     string
+	pkgPath string // Path of the package being generated
+	apiPath string // Path of this package
+
+	// Imported packages indexed by its path
+	imports map[string]string
+
+	root   *resource
+	route  *route
+	object reflect.Type
+
+	// Resources in the order they are created
+	resources []*resource
+	// The index of each Resource, by its initial value
+	resourceOf map[valueKey]int
+
+	// The index of the ID of each slice Elem type
+	idOf map[reflect.Type]int
+
+	// Mapped methods in the order they are routed
+	methods []*genMethod
+
+	// Routes in the order they are generated
+	routes []*route
+}
+
+// Identifies the initial value of a Resource
+type valueKey struct {
+	t reflect.Type
+	p uintptr
+}
+
+type genMethod struct {
+	method   *method
+	template string
+}
+
+func keyOf(v reflect.Value) valueKey {
+	return valueKey{t: v.Type(), p: v.Pointer()}
+}
+
+// Generates the whole file, formatted
+func (g *generator) generate() ([]byte, error) {
+
+	g.resourceOf = map[valueKey]int{}
+	g.idOf = map[reflect.Type]int{}
+	g.collectResources(g.root)
+	g.collectRoutes(g.route, "/"+g.route.name)
+
+	body := &bytes.Buffer{}
+
+	name := strings.Title(elemOfType(g.object).Name()) + "Router"
+
+	err := g.writeRouter(body, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range g.methods {
+		err := g.writeMethod(body, name, i, m)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "// Code generated by resoursea-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(src, "package %s\n\n", g.pkg)
+	fmt.Fprintf(src, "import (\n")
+	paths := []string{}
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if g.imports[p] == path.Base(p) {
+			fmt.Fprintf(src, "\t%q\n", p)
+			continue
+		}
+		fmt.Fprintf(src, "\t%s %q\n", g.imports[p], p)
+	}
+	fmt.Fprintf(src, ")\n\n")
+	src.Write(body.Bytes())
+
+	return format.Source(src.Bytes())
+}
+
+// Return the name used to access the package, importing it
+func (g *generator) use(pkgPath string) string {
+	alias, exist := g.imports[pkgPath]
+	if exist {
+		return alias
+	}
+	alias = path.Base(pkgPath)
+	if alias == "context" {
+		alias = "gocontext"
+	}
+	g.imports[pkgPath] = alias
+	return alias
+}
+
+// Return the qualified name of an identifier of this package
+func (g *generator) api(name string) string {
+	if g.pkgPath == g.apiPath {
+		return name
+	}
+	return g.use(g.apiPath) + "." + name
+}
+
+// Return the Go source of the type
+func (g *generator) typeName(t reflect.Type) (string, error) {
+	if t.Name() == "" {
+		switch t.Kind() {
+		case reflect.Ptr:
+			elem, err := g.typeName(t.Elem())
+			return "*" + elem, err
+		case reflect.Slice:
+			elem, err := g.typeName(t.Elem())
+			return "[]" + elem, err
+		}
+		return "", fmt.Errorf("The generator doesn't support the unnamed type %s", t)
+	}
+
+	if t.PkgPath() == "" || t.PkgPath() == g.pkgPath {
+		return t.Name(), nil
+	}
+
+	if !isExportedField(reflect.StructField{Name: t.Name()}) {
+		return "", fmt.Errorf("The generator can't access the unexported type %s", t)
+	}
+
+	return g.use(t.PkgPath()) + "." + t.Name(), nil
+}
+
+// Index the Resources in the order they are created
+// The Resources created by a Resource are its Field children, or its slice Elem
+func (g *generator) collectResources(r *resource) {
+	g.resourceOf[keyOf(r.value)] = len(g.resources)
+	g.resources = append(g.resources, r)
+
+	created := []*resource{}
+	var scan func(r *resource)
+	scan = func(parent *resource) {
+		for _, child := range append(append([]*resource{}, parent.children...), parent.extends...) {
+			if child.parent == r {
+				created = append(created, child)
+			}
+			scan(child)
+		}
+	}
+	scan(r)
+
+	sort.SliceStable(created, func(i, j int) bool {
+		return created[i].index[len(created[i].index)-1] < created[j].index[len(created[j].index)-1]
+	})
+
+	for _, child := range created {
+		g.collectResources(child)
+	}
+}
+
+// Index the Routes and its mapped methods in the order they are generated
+func (g *generator) collectRoutes(ro *route, template string) {
+	g.routes = append(g.routes, ro)
+
+	for _, key := range sortedKeys(ro.methods) {
+		m := ro.methods[key]
+		t := template
+		if _, addr := splitsMethodName(m); addr != "" {
+			t += "/" + addr
+		}
+		g.methods = append(g.methods, &genMethod{method: m, template: t})
+	}
+
+	for _, name := range sortedRouteKeys(ro.children) {
+		child := ro.children[name]
+		if ro.isSlice {
+			elem := child.value.Type()
+			if _, exist := g.idOf[elem]; !exist {
+				g.idOf[elem] = len(g.idOf)
+			}
+			g.collectRoutes(child, template+"/:"+strings.ToLower(elemOfType(elem).Name()))
+			continue
+		}
+		g.collectRoutes(child, template+"/"+name)
+	}
+}
+
+func sortedKeys(methods map[string]*method) []string {
+	keys := []string{}
+	for k := range methods {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedRouteKeys(children map[string]*route) []string {
+	keys := []string{}
+	for k := range children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Writes the Router type, its constructor, the ServeHTTP and the routing functions
+func (g *generator) writeRouter(b *bytes.Buffer, name string) error {
+
+	httpPkg := g.use("net/http")
+
+	fmt.Fprintf(b, "// Router generated for the %s Resource tree\n", g.object)
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	fmt.Fprintf(b, "// Initial state of each Resource\n")
+	for i, r := range g.resources {
+		t, err := g.typeName(r.value.Type())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "r%d %s\n", i, t)
+	}
+	fmt.Fprintf(b, "}\n\n")
+
+	object, err := g.typeName(g.object)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b, "// Creates the Router, initializing the Resource tree\n")
+	fmt.Fprintf(b, "func New%s(object %s) (*%s, error) {\n", name, object, name)
+	fmt.Fprintf(b, "h := &%s{}\n", name)
+	for i, r := range g.resources {
+		err := g.writeResource(b, i, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The Inits that depends on other Resources, after the whole tree is created
+	order, err := g.root.initOrder()
+	if err != nil {
+		return err
+	}
+	for _, r := range order {
+		err := g.writeInit(b, r)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(b, "return h, nil\n}\n\n")
+
+	fmt.Fprintf(b, "func (h *%s) ServeHTTP(w %s.ResponseWriter, req *%s.Request) {\n", name, httpPkg, httpPkg)
+	fmt.Fprintf(b, "uri := %s.Split(%s.Split(req.URL.RequestURI(), \"?\")[0], \"/\")[1:]\n", g.use("strings"), g.use("strings"))
+	fmt.Fprintf(b, "if uri[0] != %q {\n", g.route.name)
+	fmt.Fprintf(b, "%s(w, %s.New(\"Route \"+%q+\" not match with \"+uri[0]), %s.StatusNotFound)\n",
+		g.api("WriteError"), g.use("errors"), g.route.name, httpPkg)
+	fmt.Fprintf(b, "return\n}\n")
+	fmt.Fprintf(b, "ids := [%d]%s{", len(g.idOf), g.api("ID"))
+	for range g.idOf {
+		fmt.Fprintf(b, "%s, ", g.api("NilID"))
+	}
+	fmt.Fprintf(b, "}\n")
+	fmt.Fprintf(b, "m, err := h.route0(uri[1:], %s.ToLower(req.Method), &ids)\n", g.use("strings"))
+	fmt.Fprintf(b, "if err != nil {\n%s(w, err, %s.StatusNotFound)\nreturn\n}\n", g.api("WriteError"), httpPkg)
+	fmt.Fprintf(b, "switch m {\n")
+	for i := range g.methods {
+		fmt.Fprintf(b, "case %d:\nh.method%d(w, req, &ids)\n", i, i)
+	}
+	fmt.Fprintf(b, "}\n}\n\n")
+
+	for i, ro := range g.routes {
+		g.writeRoute(b, name, i, ro)
+	}
+
+	return nil
+}
+
+// Writes the code that creates and initializes the Resource
+// It follows what newResource does
+func (g *generator) writeResource(b *bytes.Buffer, i int, r *resource) error {
+
+	t, err := g.typeName(elemOfType(r.value.Type()))
+	if err != nil {
+		return err
+	}
+
+	if field, configured := configuredField(r); configured {
+		return fmt.Errorf("The generator doesn't support the configured field %s of %s", field, r.value.Type())
+	}
+
+	fmt.Fprintf(b, "v%d := new(%s)\n", i, t)
+
+	switch {
+	case r.parent == nil:
+		if g.object.Kind() == reflect.Ptr {
+			fmt.Fprintf(b, "if object != nil {\n*v%d = *object\n}\n", i)
+		} else {
+			fmt.Fprintf(b, "*v%d = object\n", i)
+		}
+	case r.parent.isSlice:
+		p := g.resourceOf[keyOf(r.parent.value)]
+		if elemOfType(r.parent.value.Type()).Elem().Kind() == reflect.Ptr {
+			fmt.Fprintf(b, "if len(*v%d) > 0 && (*v%d)[0] != nil {\n*v%d = *(*v%d)[0]\n}\n", p, p, i, p)
+		} else {
+			fmt.Fprintf(b, "if len(*v%d) > 0 {\n*v%d = (*v%d)[0]\n}\n", p, i, p)
+		}
+	default:
+		p := g.resourceOf[keyOf(r.parent.value)]
+		field := elemOfType(r.parent.value.Type()).FieldByIndex(r.index[len(r.index)-1:])
+		if field.Type.Kind() == reflect.Ptr {
+			fmt.Fprintf(b, "if v%d.%s != nil {\n*v%d = *v%d.%s\n}\n", p, field.Name, i, p, field.Name)
+		} else {
+			fmt.Fprintf(b, "*v%d = v%d.%s\n", i, p, field.Name)
+		}
+	}
+
+	fmt.Fprintf(b, "h.r%d = v%d\n", i, i)
+
+	// Embedded Resources are initialized by the Resource that embeds them
+	if r.init == nil || r.anonymous || r.initHasDependencies() {
+		return nil
+	}
+
+	return g.writeInit(b, r)
+}
+
+// Return the name of the first field of the Resource
+// filled by the configuration loading, with env, config or default tags
+func configuredField(r *resource) (string, bool) {
+	t := elemOfType(r.value.Type())
+	if t.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, configured := parseConfigTag(t.Field(i).Tag); configured {
+			return t.Field(i).Name, true
+		}
+	}
+	return "", false
+}
+
+// Return true if the Resource receiving the method or some of its outputs
+// implements api.LastModifier, so the response has the Last-Modified header
+func answersLastModified(m reflect.Method) bool {
+	types := []reflect.Type{m.Type.In(0)}
+	for i := 0; i < m.Type.NumOut(); i++ {
+		types = append(types, m.Type.Out(i))
+	}
+
+	for _, t := range types {
+		if t.Implements(lastModifierInterfaceType) ||
+			t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && ptrOfType(t).Implements(lastModifierInterfaceType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes the call of the Resource Init method
+// It follows what resource.runInit does
+func (g *generator) writeInit(b *bytes.Buffer, r *resource) error {
+
+	if r.init.Name != "Init" {
+		return fmt.Errorf("The generator doesn't support the Inits composed from the embedded Resources of %s", r.value.Type())
+	}
+
+	i := g.resourceOf[keyOf(r.value)]
+	t, err := g.typeName(elemOfType(r.value.Type()))
+	if err != nil {
+		return err
+	}
+
+	inputs := []string{}
+	for j := 1; j < r.init.Type.NumIn(); j++ {
+		in := r.init.Type.In(j)
+
+		dep, err := r.resourceOf(in)
+		if err != nil {
+			return err
+		}
+
+		// Resources out of the tree are created empty
+		v := ""
+		if dep != nil {
+			v = fmt.Sprintf("h.r%d", g.resourceOf[keyOf(dep.value)])
+		} else {
+			dt, err := g.typeName(elemOfType(in))
+			if err != nil {
+				return err
+			}
+			v = fmt.Sprintf("new(%s)", dt)
+		}
+
+		if in.Kind() == reflect.Struct || in.Kind() == reflect.Slice {
+			v = "*" + v
+		}
+		inputs = append(inputs, v)
+	}
+
+	outs := make([]string, r.init.Type.NumOut())
+	for j := range outs {
+		outs[j] = fmt.Sprintf("o%d", j)
+	}
+
+	fmt.Fprintf(b, "{\n")
+	if len(outs) > 0 {
+		fmt.Fprintf(b, "%s := ", strings.Join(outs, ", "))
+	}
+	fmt.Fprintf(b, "h.r%d.Init(%s)\n", i, strings.Join(inputs, ", "))
+
+	for j := range outs {
+		out := r.init.Type.Out(j)
+		switch {
+		case out == errorType:
+			fmt.Fprintf(b, "if o%d != nil {\nreturn nil, o%d\n}\n", j, j)
+		case ptrOfType(out) == r.value.Type():
+			fmt.Fprintf(b, "h.r%d = new(%s)\n", i, t)
+			if out.Kind() == reflect.Ptr {
+				fmt.Fprintf(b, "if o%d != nil {\n*h.r%d = *o%d\n}\n", j, i, j)
+			} else {
+				fmt.Fprintf(b, "*h.r%d = o%d\n", i, j)
+			}
+		case isEmbeddedOutput(r.value.Type(), out):
+			writeEmbeddedOutput(b, fmt.Sprintf("h.r%d", i), r.value.Type(), out, j)
+		default:
+			fmt.Fprintf(b, "_ = o%d\n", j)
+		}
+	}
+	fmt.Fprintf(b, "}\n")
+
+	return nil
+}
+
+// Writes the routing function of the Route
+// It follows what route.method does
+func (g *generator) writeRoute(b *bytes.Buffer, name string, i int, ro *route) {
+
+	errorsPkg := g.use("errors")
+
+	fmt.Fprintf(b, "func (h *%s) route%d(uri []string, httpMethod string, ids *[%d]%s) (int, error) {\n",
+		name, i, len(g.idOf), g.api("ID"))
+
+	fmt.Fprintf(b, "if len(uri) == 0 {\nswitch httpMethod {\n")
+	for _, key := range sortedKeys(ro.methods) {
+		fmt.Fprintf(b, "case %q:\nreturn %d, nil\n", key, g.methodIndex(ro.methods[key]))
+	}
+	fmt.Fprintf(b, "}\nreturn -1, %s.New(\"Method \" + httpMethod + \" not found in the \" + %q)\n}\n", errorsPkg, ro.String())
+
+	if len(ro.methods) > 0 {
+		fmt.Fprintf(b, "if len(uri) == 1 {\nswitch httpMethod + uri[0] {\n")
+		for _, key := range sortedKeys(ro.methods) {
+			fmt.Fprintf(b, "case %q:\nreturn %d, nil\n", key, g.methodIndex(ro.methods[key]))
+		}
+		fmt.Fprintf(b, "}\n}\n")
+	}
+
+	if ro.isSlice {
+		for _, child := range ro.children {
+			fmt.Fprintf(b, "ids[%d] = %s(uri[0])\n", g.idOf[child.value.Type()], g.api("NewID"))
+			fmt.Fprintf(b, "return h.route%d(uri[1:], httpMethod, ids)\n}\n\n", g.routeIndex(child))
+			return
+		}
+		fmt.Fprintf(b, "return -1, %s.New(\"Route \" + %q + \" is an slice and has no child!\")\n}\n\n", errorsPkg, ro.String())
+		return
+	}
+
+	if len(ro.children) > 0 {
+		fmt.Fprintf(b, "switch uri[0] {\n")
+		for _, key := range sortedRouteKeys(ro.children) {
+			fmt.Fprintf(b, "case %q:\nreturn h.route%d(uri[1:], httpMethod, ids)\n", key, g.routeIndex(ro.children[key]))
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+
+	fmt.Fprintf(b, "return -1, %s.New(\"Not exist any Child '\" + uri[0] + \"' or Action '\" + httpMethod + %s.Title(uri[0]) + \"' in the \" + %q)\n}\n\n",
+		errorsPkg, g.use("strings"), ro.String())
+}
+
+func (g *generator) methodIndex(m *method) int {
+	for i, gm := range g.methods {
+		if gm.method == m {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *generator) routeIndex(ro *route) int {
+	for i, r := range g.routes {
+		if r == ro {
+			return i
+		}
+	}
+	return -1
+}
+
+// Writes the function that answers the requests for the mapped method
+// It follows the method plan, like context.run does
+func (g *generator) writeMethod(b *bytes.Buffer, name string, i int, gm *genMethod) error {
+
+	m := gm.method
+	p := m.plan
+	httpPkg := g.use("net/http")
+
+	if m.generated {
+		return fmt.Errorf("The generator doesn't support the methods generated by the Store of %s", m.method.Type.In(0))
+	}
+	if p.list != nil {
+		return fmt.Errorf("The generator doesn't support the api.ListParams asked by %s", m)
+	}
+	if p.links {
+		return fmt.Errorf("The generator doesn't support the *api.Links asked by %s", m)
+	}
+	if m.cache != nil {
+		return fmt.Errorf("The generator doesn't support the cache policy of %s", m)
+	}
+	if answersLastModified(m.method) {
+		return fmt.Errorf("The generator doesn't support the Last-Modified answered by %s", m)
+	}
+
+	fmt.Fprintf(b, "// %s\n", m)
+	fmt.Fprintf(b, "func (h *%s) method%d(w %s.ResponseWriter, req *%s.Request, ids *[%d]%s) {\n",
+		name, i, httpPkg, httpPkg, len(g.idOf), g.api("ID"))
+
+	fmt.Fprintf(b, "req = %s(req, &%s{\nTemplate: %q,\nHTTPMethod: req.Method,\nMethod: %q,\nResource: %q,\n})\n",
+		g.api("WithRoute"), g.api("RouteInfo"), gm.template, m.method.Name, m.method.Type.In(0).String())
+	fmt.Fprintf(b, "ctx := req.Context()\n_ = ctx\n")
+	fmt.Fprintf(b, "errs := []error{}\n_ = errs\n")
+
+	// Declare all slots, so the teardown can see them
+	for _, s := range p.steps {
+		if s.dependency.scope == SingletonScope {
+			return fmt.Errorf("The generator doesn't support the singleton %s", s.dependency.value.Type())
+		}
+		if s.dependency.collection != nil || s.dependency.store != nil {
+			return fmt.Errorf("The generator doesn't support the element of collection %s", s.dependency.value.Type())
+		}
+		if s.dependency.constructor != nil && s.dependency.constructor.Name != "New" {
+			return fmt.Errorf("The generator doesn't support the dependency %s", s.dependency.value.Type())
+		}
+		t, err := g.typeName(s.dependency.value.Type())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "var s%d %s\n", s.slot, t)
+	}
+
+	// The steps after one that stops the request run only if it didn't fail
+	stops := false
+	for _, s := range p.steps {
+		stops = stops || s.stop
+	}
+	if stops {
+		fmt.Fprintf(b, "var failure error\n")
+	}
+
+	args := 0
+	stopped := false
+	for _, s := range p.steps {
+		if stopped {
+			fmt.Fprintf(b, "if failure == nil {\n")
+		}
+		err := g.writeStep(b, s, &args)
+		if err != nil {
+			return err
+		}
+		if stopped {
+			fmt.Fprintf(b, "}\n")
+		}
+		stopped = stopped || s.stop
+	}
+
+	// The teardown of the dependencies, in the reverse order
+	fmt.Fprintf(b, "teardown := func(outcome error) {\n")
+	for j := len(p.steps) - 1; j >= 0; j-- {
+		s := p.steps[j]
+		t := s.dependency.value.Type()
+		if t.Implements(donerInterfaceType) {
+			fmt.Fprintf(b, "if s%d != nil {\ns%d.Done(outcome)\n}\n", s.slot, s.slot)
+		}
+		if t.Implements(closerInterfaceType) {
+			fmt.Fprintf(b, "if s%d != nil {\nif err := s%d.Close(); err != nil {\n%s.Printf(\"Error closing the dependency %%s: %%s\", %q, err)\n}\n}\n",
+				s.slot, s.slot, g.use("log"), t.String())
+		}
+	}
+	fmt.Fprintf(b, "_ = outcome\n}\n")
+
+	if stops {
+		fmt.Fprintf(b, "if failure != nil {\n%s(w, failure, %s(failure))\nteardown(failure)\nreturn\n}\n",
+			g.api("WriteError"), g.api("ErrorStatus"))
+	}
+	fmt.Fprintf(b, "if err := ctx.Err(); err != nil {\n%s(w, err, %s(err))\nteardown(err)\nreturn\n}\n",
+		g.api("WriteError"), g.api("ErrorStatus"))
+
+	inputs, err := g.writeArguments(b, p.args, &args)
+	if err != nil {
+		return err
+	}
+
+	outs := make([]string, m.method.Type.NumOut())
+	ptrs := make([]string, len(outs))
+	names := make([]string, len(outs))
+	for j := range outs {
+		outs[j] = fmt.Sprintf("o%d", j)
+		ptrs[j] = "&" + outs[j]
+		names[j] = strconv.Quote(m.outName[j])
+	}
+
+	if len(outs) > 0 {
+		fmt.Fprintf(b, "%s := ", strings.Join(outs, ", "))
+	}
+	fmt.Fprintf(b, "%s.%s(%s)\n", inputs[0], m.method.Name, strings.Join(inputs[1:], ", "))
+
+	fmt.Fprintf(b, "teardown(%s(w, []string{%s}", g.api("WriteOutput"), strings.Join(names, ", "))
+	for _, ptr := range ptrs {
+		fmt.Fprintf(b, ", %s", ptr)
+	}
+	fmt.Fprintf(b, "))\n}\n\n")
+
+	return nil
+}
+
+// Writes the construction of one dependency
+// It follows what context.runStep does
+func (g *generator) writeStep(b *bytes.Buffer, s *step, args *int) error {
+
+	d := s.dependency
+	t, err := g.typeName(elemOfType(d.value.Type()))
+	if err != nil {
+		return err
+	}
+
+	// The initial value of the dependency
+	fmt.Fprintf(b, "s%d = new(%s)\n", s.slot, t)
+	if r, exist := g.resourceOf[keyOf(d.value)]; exist {
+		fmt.Fprintf(b, "*s%d = *h.r%d\n", s.slot, r)
+	}
+
+	// Values without constructor are validated as they are
+	if d.constructor == nil {
+		if d.validator == nil {
+			return nil
+		}
+		fmt.Fprintf(b, "{\nev := %s(s%d)\n", g.api("Validate"), s.slot)
+		err := g.writeConstructorError(b, s, "ev")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "}\n")
+		return nil
+	}
+
+	fmt.Fprintf(b, "if ctx.Err() == nil {\n")
+
+	inputs, err := g.writeArguments(b, s.args, args)
+	if err != nil {
+		return err
+	}
+
+	outs := make([]string, d.constructor.Type.NumOut())
+	for j := range outs {
+		outs[j] = fmt.Sprintf("o%d", j)
+	}
+	if len(outs) > 0 {
+		fmt.Fprintf(b, "%s := ", strings.Join(outs, ", "))
+	}
+	fmt.Fprintf(b, "s%d.New(%s)\n", s.slot, strings.Join(inputs, ", "))
+
+	// The validation runs just if the constructor didn't fail
+	validate := "{\n"
+
+	for j := range outs {
+		out := d.constructor.Type.Out(j)
+		switch {
+		case out == errorType:
+			err := g.writeConstructorError(b, s, fmt.Sprintf("o%d", j))
+			if err != nil {
+				return err
+			}
+			validate = fmt.Sprintf("if o%d == nil {\n", j)
+		case d.isType(out) && out.Kind() == reflect.Ptr:
+			fmt.Fprintf(b, "s%d = o%d\n", s.slot, j)
+		case d.isType(out):
+			fmt.Fprintf(b, "s%d = new(%s)\n*s%d = o%d\n", s.slot, t, s.slot, j)
+		case isEmbeddedOutput(d.value.Type(), out):
+			writeEmbeddedOutput(b, fmt.Sprintf("s%d", s.slot), d.value.Type(), out, j)
+		default:
+			fmt.Fprintf(b, "_ = o%d\n", j)
+		}
+	}
+
+	if d.validator != nil {
+		fmt.Fprintf(b, "%sev := %s(s%d)\n", validate, g.api("Validate"), s.slot)
+		err := g.writeConstructorError(b, s, "ev")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+
+	fmt.Fprintf(b, "}\n")
+
+	return nil
+}
+
+// Return true if the output of a promoted Init or New is an embedded Resource of the owner
+func isEmbeddedOutput(owner reflect.Type, out reflect.Type) bool {
+	_, exist := embeddedIndex(owner, out)
+	return exist
+}
+
+// Writes the storing of the output of a promoted Init or New in its embedded field
+// It follows what setPromotedOutput does
+func writeEmbeddedOutput(b *bytes.Buffer, v string, owner reflect.Type, out reflect.Type, j int) {
+	index, _ := embeddedIndex(owner, out)
+
+	ft := elemOfType(owner)
+	for _, k := range index {
+		v += "." + ft.Field(k).Name
+		ft = ft.Field(k).Type
+	}
+
+	if out.Kind() == reflect.Ptr {
+		fmt.Fprintf(b, "if o%d != nil {\n%s = *o%d\n}\n", j, v, j)
+	} else {
+		fmt.Fprintf(b, "%s = o%d\n", v, j)
+	}
+}
+
+// Writes the handling of the error outputed by the step constructor,
+// or by the validation of the constructed value
+// It follows what context.constructWith and context.runStep do
+func (g *generator) writeConstructorError(b *bytes.Buffer, s *step, out string) error {
+
+	t, err := g.typeName(s.dependency.value.Type())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b, "if %s != nil {\n", out)
+	fmt.Fprintf(b, "var e error = &%s{Type: %s.TypeOf((%s)(nil)), Err: %s}\n",
+		g.api("DependencyError"), g.use("reflect"), t, out)
+	fmt.Fprintf(b, "errs = append(errs, e)\n")
+
+	if s.stop {
+		conditions := []string{}
+		for _, h := range s.handles {
+			ht, err := g.typeName(h)
+			if err != nil {
+				return err
+			}
+			conditions = append(conditions, fmt.Sprintf("!%s.As(e, new(%s))", g.use("errors"), ht))
+		}
+		if len(conditions) > 0 {
+			fmt.Fprintf(b, "if %s {\nfailure = e\n}\n", strings.Join(conditions, " && "))
+		} else {
+			fmt.Fprintf(b, "failure = e\n")
+		}
+	}
+
+	fmt.Fprintf(b, "}\n")
+
+	return nil
+}
+
+// Writes the arguments that need to be computed before the call
+// and return the expression of each argument
+func (g *generator) writeArguments(b *bytes.Buffer, args []argument, count *int) ([]string, error) {
+	inputs := make([]string, len(args))
+
+	for j, a := range args {
+		n := fmt.Sprintf("a%d", *count)
+		*count++
+
+		switch a.kind {
+		case errorArgument:
+			fmt.Fprintf(b, "var %s error\nif len(errs) > 0 {\n%s = errs[0]\n}\n", n, n)
+			inputs[j] = n
+		case errorSliceArgument:
+			fmt.Fprintf(b, "%s := make([]error, len(errs))\ncopy(%s, errs)\n", n, n)
+			inputs[j] = n
+		case typedErrorArgument:
+			t, err := g.typeName(a.t)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(b, "var %s %s\nfor _, e := range errs {\nif %s.As(e, &%s) {\nbreak\n}\n}\n",
+				n, t, g.use("errors"), n)
+			inputs[j] = n
+		case contextArgument:
+			inputs[j] = "ctx"
+		case idArgument:
+			index, exist := g.idOf[a.requester]
+			if !exist {
+				inputs[j] = g.api("NilID")
+				continue
+			}
+			inputs[j] = fmt.Sprintf("ids[%d]", index)
+		default:
+			switch {
+			case a.slot == writerSlot:
+				inputs[j] = "w"
+			case a.slot == requestSlot:
+				inputs[j] = "req"
+			case a.elem:
+				t, err := g.typeName(a.t)
+				if err != nil {
+					return nil, err
+				}
+				fmt.Fprintf(b, "var %s %s\nif s%d != nil {\n%s = *s%d\n}\n", n, t, a.slot, n, a.slot)
+				inputs[j] = n
+			default:
+				inputs[j] = fmt.Sprintf("s%d", a.slot)
+			}
+		}
+	}
+
+	return inputs, nil
+}
@@ -0,0 +1,107 @@
+// This package tests the Router generator
+package api
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The generated file for the api Resource tree
+// Update it with: go test -run TestGenerate -update
+const generatedFile = "api_gen_test.go"
+
+var update = flag.Bool("update", false, "update the generated files")
+
+// Testing if the generated file is up to date with the generator
+func TestGenerate(t *testing.T) {
+	var src bytes.Buffer
+	err := Generate(&src, "api", api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		err = os.WriteFile(generatedFile, src.Bytes(), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	generated, err := os.ReadFile(generatedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(src.Bytes(), generated) {
+		t.Fatalf("The file %s is out of date, run: go test -run TestGenerate -update", generatedFile)
+	}
+}
+
+// Testing if the generated Router answers like the reflective Router
+func TestGeneratedRouter(t *testing.T) {
+	rt, err := NewRouter(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := NewAPIRouter(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []struct{ method, uri string }{
+		{"GET", "/api/version"},
+		{"GET", "/api/gophers"},
+		{"GET", "/api/gophers/1"},
+		{"GET", "/api/gophers/2/message"},
+		{"GET", "/api/gophers/abc"},
+		{"GET", "/api/gophers/abc/message"},
+		{"GET", "/api/dogbark"},
+		{"POST", "/api/version"},
+		{"GET", "/api/nothing"},
+		{"GET", "/other"},
+	}
+
+	for _, r := range requests {
+		w1 := httptest.NewRecorder()
+		w2 := httptest.NewRecorder()
+
+		req, err := http.NewRequest(r.method, r.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rt.ServeHTTP(w1, req)
+		gen.ServeHTTP(w2, req)
+
+		if w1.Code != w2.Code || !bytes.Equal(w1.Body.Bytes(), w2.Body.Bytes()) {
+			t.Fatalf("[%s] %s answered differently:\n%d %s\n%d %s",
+				r.method, r.uri, w1.Code, w1.Body, w2.Code, w2.Body)
+		}
+	}
+}
+
+// Testing if the generator refuses the features the generated Routers don't support
+func TestGenerateUnsupported(t *testing.T) {
+	cases := []struct {
+		object  interface{}
+		problem string
+	}{
+		{Portal{}, "cache policy"},
+		{Newsroom{}, "Last-Modified"},
+		{Service{}, "configured field"},
+	}
+
+	for _, c := range cases {
+		err := Generate(io.Discard, "api", c.object)
+		if err == nil || !strings.Contains(err.Error(), c.problem) {
+			t.Errorf("Expected an error about the %s of %T, got %v", c.problem, c.object, err)
+		}
+	}
+}
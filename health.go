@@ -0,0 +1,203 @@
+package api
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resources implementing this interface are checked by the health endpoints
+// Tag the Resource field with `health:"optional"` if its failure
+// shouldn't make the whole service unhealthy
+type HealthChecker interface {
+	Health(ctx gocontext.Context) error
+}
+
+var healthCheckerInterfaceType = reflect.TypeOf((*HealthChecker)(nil)).Elem()
+
+// Status of the health reports
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // Just optional Resources failed
+	HealthFail     = "fail"
+)
+
+// The JSON answered by the health endpoints
+type HealthReport struct {
+	Status string `json:"status"`
+
+	// Why the service isn't ready, when it isn't caused by a Resource
+	Error string `json:"error,omitempty"`
+
+	// The report of each Resource checked, indexed by its path in the tree
+	Resources map[string]*ResourceHealth `json:"resources"`
+}
+
+// The health of one Resource
+type ResourceHealth struct {
+	Status   string `json:"status"`
+	Latency  string `json:"latency"`
+	Optional bool   `json:"optional,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Serve the health report of the Resource tree in the path, like /healthz
+// It answers 503 if some required Resource fails
+func (ro *route) HealthCheck(path string) {
+	ro.healthPath = path
+}
+
+// Serve the readiness report of the Resource tree in the path, like /readyz
+// It is the health report, but it also answers 503 after the shutdown has started
+func (ro *route) ReadinessCheck(path string) {
+	ro.readyPath = path
+}
+
+// Answer the health endpoints
+// It returns false if the request isn't for one of them
+func (ro *route) serveHealth(w http.ResponseWriter, req *http.Request) bool {
+	path := req.URL.Path
+	if path == "" || path != ro.healthPath && path != ro.readyPath {
+		return false
+	}
+
+	var report *HealthReport
+	if path == ro.readyPath && ro.lifecycle.isClosing() {
+		report = &HealthReport{
+			Status:    HealthFail,
+			Error:     errShuttingDown.Error(),
+			Resources: map[string]*ResourceHealth{},
+		}
+	} else {
+		report = ro.lifecycle.checkHealth(req.Context())
+	}
+
+	status := http.StatusOK
+	if report.Status == HealthFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	jsonResponse, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(jsonResponse)
+
+	return true
+}
+
+// Check the health of all the Resources of the tree concurrently
+func (lc *lifecycle) checkHealth(ctx gocontext.Context) *HealthReport {
+	report := &HealthReport{
+		Status:    HealthOK,
+		Resources: map[string]*ResourceHealth{},
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, r := range lc.resources {
+		if !r.isHealthChecked() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *resource) {
+			defer wg.Done()
+
+			h := r.checkHealth(ctx)
+
+			mutex.Lock()
+			report.Resources[r.path()] = h
+			mutex.Unlock()
+		}(r)
+	}
+	wg.Wait()
+
+	for _, h := range report.Resources {
+		if h.Status == HealthOK {
+			continue
+		}
+		if !h.Optional {
+			report.Status = HealthFail
+			break
+		}
+		report.Status = HealthDegraded
+	}
+
+	return report
+}
+
+// Return true if the Resource should be checked by the health endpoints
+// The Elem Resources of slices are just the initial value of its Elems, they aren't checked
+func (r *resource) isHealthChecked() bool {
+	if r.parent != nil && r.parent.isSlice {
+		return false
+	}
+	return !r.value.IsNil() && r.value.Type().Implements(healthCheckerInterfaceType)
+}
+
+// Call the Health method of the Resource, measuring its latency
+// A panic in the Health method is reported as a failure
+func (r *resource) checkHealth(ctx gocontext.Context) (h *ResourceHealth) {
+	h = &ResourceHealth{
+		Status:   HealthOK,
+		Optional: r.tag.Get("health") == "optional",
+	}
+
+	start := time.Now()
+	defer func() {
+		h.Latency = time.Since(start).String()
+		if value := recover(); value != nil {
+			h.Status = HealthFail
+			h.Error = fmt.Sprintf("Health check panicked: %v", value)
+		}
+	}()
+
+	err := r.currentValue().Interface().(HealthChecker).Health(ctx)
+	if err != nil {
+		h.Status = HealthFail
+		h.Error = err.Error()
+	}
+
+	return h
+}
+
+// Return the value the requests are answered with
+// The singleton constructed from the Resource replaces its initial value
+func (r *resource) currentValue() reflect.Value {
+	s, exist := r.root().singletons[r]
+	if !exist {
+		return r.value
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.built {
+		return s.value
+	}
+	return r.value
+}
+
+// Return the path of the Resource in the tree, like /api/database
+func (r *resource) path() string {
+	names := []string{}
+	for res := r; res != nil; res = res.parent {
+		// Embedded Resources aren't part of the URI
+		if res.anonymous {
+			continue
+		}
+		names = append([]string{res.name}, names...)
+	}
+	return "/" + strings.Join(names, "/")
+}
@@ -0,0 +1,138 @@
+// This package tests the health and readiness endpoints
+package api
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var primaryErr error
+
+type Clinic struct {
+	Primary Primary
+	Replica Replica `health:"optional"`
+}
+
+type Primary struct{}
+
+func (p *Primary) Health(ctx gocontext.Context) error {
+	return primaryErr
+}
+
+func (p *Primary) GET() string {
+	return "primary"
+}
+
+type Replica struct{}
+
+func (r *Replica) Health(ctx gocontext.Context) error {
+	return errors.New("replication lag")
+}
+
+// The singleton is checked once it is constructed
+type Lab struct {
+	Analyzer Analyzer `scope:"singleton"`
+}
+
+type Analyzer struct {
+	Calibrated bool
+}
+
+func (a *Analyzer) New() *Analyzer {
+	a.Calibrated = true
+	return a
+}
+
+func (a *Analyzer) GET() bool {
+	return a.Calibrated
+}
+
+func (a *Analyzer) Health(ctx gocontext.Context) error {
+	if !a.Calibrated {
+		return errors.New("not calibrated")
+	}
+	return nil
+}
+
+func healthOf(t *testing.T, rt Router, path string) (int, HealthReport) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	rt.ServeHTTP(w, req)
+
+	var report HealthReport
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, report
+}
+
+func TestHealthCheckSingleton(t *testing.T) {
+	rt, err := NewRouter(Lab{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.HealthCheck("/healthz")
+
+	_, report := healthOf(t, rt, "/healthz")
+	if report.Resources["/lab/analyzer"].Status != HealthFail {
+		t.Errorf("Expected the initial value checked before the singleton is constructed, got %+v",
+			report.Resources["/lab/analyzer"])
+	}
+
+	errorTest(doRequest(rt, "GET", "/lab/analyzer", ""), t)
+
+	code, report := healthOf(t, rt, "/healthz")
+	if code != http.StatusOK || report.Resources["/lab/analyzer"].Status != HealthOK {
+		t.Errorf("Expected the constructed singleton checked, got %d %+v",
+			code, report.Resources["/lab/analyzer"])
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	rt, err := NewRouter(Clinic{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.HealthCheck("/healthz")
+	rt.ReadinessCheck("/readyz")
+
+	primaryErr = nil
+	code, report := healthOf(t, rt, "/healthz")
+	if code != http.StatusOK || report.Status != HealthDegraded {
+		t.Errorf("Expected a degraded report answered as 200, got %d %s", code, report.Status)
+	}
+	if len(report.Resources) != 2 ||
+		report.Resources["/clinic/primary"].Status != HealthOK ||
+		report.Resources["/clinic/replica"].Status != HealthFail ||
+		!report.Resources["/clinic/replica"].Optional ||
+		report.Resources["/clinic/replica"].Error != "replication lag" {
+		t.Errorf("Unexpected Resources report: %+v", report.Resources)
+	}
+
+	primaryErr = errors.New("connection refused")
+	code, report = healthOf(t, rt, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != HealthFail {
+		t.Errorf("Expected a failed report answered as 503, got %d %s", code, report.Status)
+	}
+
+	primaryErr = nil
+	err = rt.Shutdown(gocontext.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, report = healthOf(t, rt, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Error == "" {
+		t.Errorf("Expected not ready after the shutdown, got %d %+v", code, report)
+	}
+
+	code, _ = healthOf(t, rt, "/healthz")
+	if code != http.StatusOK {
+		t.Errorf("Expected healthy after the shutdown, got %d", code)
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Return true if the Init method receives other Resources
+func (r *resource) initHasDependencies() bool {
+	return r.init != nil && r.init.Type.NumIn() > 1
+}
+
+// Call the Init method of the Resource
+// It receives the initial value of the Resource and the Resources it depends on,
+// that should be already initialized
+func (r *resource) runInit() error {
+
+	inputs, err := r.initInputs()
+	if err != nil {
+		return err
+	}
+
+	// Each element of a collection is initialized
+	if r.isElement() {
+		return r.runElementInits(inputs)
+	}
+
+	out := r.init.Func.Call(inputs)
+	for _, v := range out {
+		// Test is Init returned an error
+		if v.Type() == errorType && !v.IsNil() {
+			return v.Interface().(error)
+		}
+		if ptrOfType(v.Type()) == r.value.Type() {
+			r.value = ptrOfValue(v)
+			continue
+		}
+		// Promoted Inits output the embedded Resource
+		setPromotedOutput(r.value, v)
+	}
+
+	return nil
+}
+
+// Return the inputs of the Init method
+// The Resources it depends on are found in the Resource tree
+func (r *resource) initInputs() ([]reflect.Value, error) {
+	inputs := make([]reflect.Value, r.init.Type.NumIn())
+	inputs[0] = r.value
+
+	for i := 1; i < len(inputs); i++ {
+		t := r.init.Type.In(i)
+
+		v, err := r.valueOf(t)
+		if err != nil {
+			return nil, err
+		}
+
+		// If it is requiring the Elem itself or the Slice itself
+		if t.Kind() == reflect.Struct || t.Kind() == reflect.Slice {
+			v = v.Elem()
+		}
+		inputs[i] = v
+	}
+
+	return inputs, nil
+}
+
+// Return the Resources whose Init methods depends on other Resources,
+// in the order they should be initialized
+// The Resources are initialized after the ones they depend on
+// Return an error if there is a circular dependency between the Inits
+func (r *resource) initOrder() ([]*resource, error) {
+	s := &initSorter{
+		cd:    &circularDependency{dependents: []reflect.Type{}},
+		done:  map[*resource]bool{},
+		order: []*resource{},
+	}
+
+	err := s.scan(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.order, nil
+}
+
+// Sorts the Inits that depends on other Resources
+type initSorter struct {
+	cd    *circularDependency
+	done  map[*resource]bool
+	order []*resource
+}
+
+// Visit all the Resources of the tree in the order they were created
+func (s *initSorter) scan(r *resource) error {
+	if r.initHasDependencies() && !r.anonymous {
+		// The children were created with the values before the Init,
+		// so what it writes in their fields would be lost
+		if len(r.children) > 0 {
+			return fmt.Errorf("The Init of %s depends on other Resources, so it runs after its children were created, "+
+				"move its dependencies to the constructor or the Init of its children", r.value.Type())
+		}
+
+		err := s.visit(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, child := range append(append([]*resource{}, r.children...), r.extends...) {
+		err := s.scan(child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add the Resource to the order after the Resources its Init depends on
+func (s *initSorter) visit(r *resource) error {
+	if s.done[r] {
+		return nil
+	}
+
+	err := s.cd.addAndCheck(r.value.Type())
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < r.init.Type.NumIn(); i++ {
+		dep, err := r.resourceOf(r.init.Type.In(i))
+		if err != nil {
+			return err
+		}
+
+		// Resources out of the tree or initialized while the tree was created
+		// are ready to be injected
+		if dep == nil || !dep.initHasDependencies() {
+			continue
+		}
+
+		err = s.visit(dep)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.cd.pop()
+
+	s.done[r] = true
+	s.order = append(s.order, r)
+
+	return nil
+}
+
+// Call the Init methods that depends on other Resources,
+// in the order they should be initialized
+// Return the Resources initialized, in that order
+func (r *resource) runDependentInits() ([]*resource, error) {
+	order, err := r.initOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range order {
+		err := res.runInit()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
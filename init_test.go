@@ -1,3 +1,4 @@
+// This package tests the Init methods, also the ones that depends on other Resources
 package api
 
 import (
@@ -5,9 +6,97 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
+var inits []string
+
+type Backend struct {
+	Settings Settings
+	Storage  Storage
+}
+
+type Settings struct {
+	DSN string
+}
+
+func (s *Settings) Init() *Settings {
+	inits = append(inits, "settings")
+	if s.DSN == "" {
+		s.DSN = "memory://"
+	}
+	return s
+}
+
+type Storage struct {
+	Warmer     Warmer
+	Repository Repository
+}
+
+type Repository struct {
+	DSN  string
+	Warm bool
+}
+
+// Settings is declared higher up the tree
+func (r *Repository) Init(s *Settings) (*Repository, error) {
+	inits = append(inits, "repository")
+	r.DSN = s.DSN
+	return r, nil
+}
+
+func (r *Repository) GET() *Repository {
+	return r
+}
+
+type Warmer struct{}
+
+// Warmer is declared before the Repository, but it is initialized after it
+func (w Warmer) Init(r *Repository) {
+	inits = append(inits, "warmer")
+	r.Warm = r.DSN != ""
+}
+
+type Loop struct {
+	Egg Egg
+	Hen Hen
+}
+
+type Egg struct{}
+
+func (e *Egg) Init(h Hen) {}
+
+type Hen struct{}
+
+func (h *Hen) Init(e *Egg) {}
+
+// The Init of the Cabinet would write in the Drawer after it was created
+type Depository struct {
+	Settings Settings
+	Cabinet  Cabinet
+}
+
+type Cabinet struct {
+	Drawer Drawer
+}
+
+func (c *Cabinet) Init(s *Settings) {
+	c.Drawer.DSN = s.DSN
+}
+
+type Drawer struct {
+	DSN string
+}
+
+func (d *Drawer) GET() *Drawer {
+	return d
+}
+
+type RepositoryResp struct {
+	Repository Repository
+}
+
 func TestVersionInit(t *testing.T) {
 	rt, err := NewRouter(api)
 	if err != nil {
@@ -39,3 +128,48 @@ func TestVersionInit(t *testing.T) {
 		t.Fatal("Version wasn't initialized correctly")
 	}
 }
+
+func TestInitDependencies(t *testing.T) {
+	inits = nil
+
+	rt, err := NewRouter(Backend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(inits, " ") != "settings repository warmer" {
+		t.Fatalf("Inits didn't run in the dependencies order: %v", inits)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/backend/storage/repository", nil)
+	rt.ServeHTTP(w, req)
+
+	var resp RepositoryResp
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Repository.DSN != "memory://" || !resp.Repository.Warm {
+		t.Errorf("Repository wasn't initialized with its dependencies: %s", w.Body)
+	}
+}
+
+func TestInitCircularDependency(t *testing.T) {
+	_, err := NewRouter(Loop{})
+	if err == nil {
+		t.Fatal("Expected an error for the circular dependency between the Inits")
+	}
+
+	if !strings.Contains(err.Error(), "*api.Egg depends on *api.Hen that depends on *api.Egg") {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestInitDependenciesWithChildren(t *testing.T) {
+	_, err := NewRouter(Depository{})
+	if err == nil || !strings.Contains(err.Error(), "Cabinet") {
+		t.Fatalf("Expected an error for the Init depending on other Resources with children, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//
+// The Routes can answer the links of the requested Resource,
+// built from the Route tree: the Resource itself as self,
+// its children and its actions, each one with the HTTP methods it accepts
+// The links of the elements include the IDs in the URI
+//
+
+// Where the links are answered
+type LinkStyle int
+
+const (
+	// The links aren't answered
+	// This is the default style
+	NoLinks LinkStyle = iota
+
+	// The links are answered in the _links member of the JSON response
+	LinksMember
+
+	// The links are answered in the Link header
+	LinksHeader
+)
+
+var linksPtrType = reflect.TypeOf((*Links)(nil))
+
+// One link of the Resource
+type Link struct {
+	Href    string   `json:"href"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// The links answered with the Resource, indexed by their relation
+// Methods and constructors can ask for *api.Links to add or remove links
+type Links struct {
+	mu    sync.Mutex
+	links map[string]Link
+}
+
+func newLinks() *Links {
+	return &Links{links: map[string]Link{}}
+}
+
+// Return the Links injected in the arguments of the plan,
+// or nil if nobody asks for them
+// When the links are answered, the Router replaces them by the links of the Route
+func (p *plan) newLinks() *Links {
+	if p == nil || !p.links {
+		return nil
+	}
+	return newLinks()
+}
+
+// Add the link with the relation, replacing the one that already exists
+func (l *Links) Add(rel, href string, methods ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.links[rel] = Link{Href: href, Methods: methods}
+}
+
+// Remove the link with the relation
+func (l *Links) Remove(rel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.links, rel)
+}
+
+// Return the link with the relation
+func (l *Links) Get(rel string) (Link, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	link, exist := l.links[rel]
+	return link, exist
+}
+
+// Return a copy of the links, to be encoded in the response
+func (l *Links) all() map[string]Link {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	links := make(map[string]Link, len(l.links))
+	for rel, link := range l.links {
+		links[rel] = link
+	}
+	return links
+}
+
+// Return the links formatted as the Link header
+// The self link comes first, and the others are sorted by their relation
+func (l *Links) header() string {
+	links := l.all()
+
+	rels := make([]string, 0, len(links))
+	for rel := range links {
+		if rel != "self" {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+	if _, exist := links["self"]; exist {
+		rels = append([]string{"self"}, rels...)
+	}
+
+	values := make([]string, len(rels))
+	for i, rel := range rels {
+		values[i] = fmt.Sprintf("<%s>; rel=\"%s\"", links[rel].Href, rel)
+		if len(links[rel].Methods) > 0 {
+			values[i] += fmt.Sprintf("; methods=\"%s\"", strings.Join(links[rel].Methods, " "))
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// Define where the links of this Route and its children are answered
+// The style defined in a child Route overrides the one defined in its parents
+func (ro *route) Hypermedia(style LinkStyle) {
+	ro.linkStyle = style
+	ro.linkStyleDefined = true
+}
+
+// Return the links of the Resource of this Route, found in the href
+// Slices don't link their elements, since they need an ID
+func (ro *route) links(href string) *Links {
+	l := newLinks()
+
+	actions := map[string][]string{}
+	self := []string{}
+	for _, m := range ro.methods {
+		httpMethod, addr := splitsMethodName(m)
+		if addr == "" {
+			self = append(self, httpMethod)
+			continue
+		}
+		actions[addr] = append(actions[addr], httpMethod)
+	}
+
+	l.Add("self", href, sortedMethods(self)...)
+
+	for addr, methods := range actions {
+		l.Add(addr, href+"/"+addr, sortedMethods(methods)...)
+	}
+
+	if !ro.isSlice {
+		for name, child := range ro.children {
+			methods := []string{}
+			for _, m := range child.methods {
+				if httpMethod, addr := splitsMethodName(m); addr == "" {
+					methods = append(methods, httpMethod)
+				}
+			}
+			l.Add(name, href+"/"+name, sortedMethods(methods)...)
+		}
+	}
+
+	return l
+}
+
+func sortedMethods(methods []string) []string {
+	sort.Strings(methods)
+	return methods
+}
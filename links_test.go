@@ -0,0 +1,129 @@
+// This package tests the hypermedia links of the responses
+package api
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type Zoo struct {
+	Animals Animals
+	Keeper  Keeper
+}
+
+func (z *Zoo) GET() string {
+	return "zoo"
+}
+
+type Animals []Animal
+
+func (a Animals) GET() Animals {
+	return a
+}
+
+type Animal struct {
+	Name string
+}
+
+func (a *Animal) GET() *Animal {
+	return a
+}
+
+func (a *Animal) DELETE() {}
+
+func (a *Animal) POSTLike() string {
+	return "liked"
+}
+
+func (a *Animal) GETLike() string {
+	return "likes"
+}
+
+type Keeper struct{}
+
+// Methods can change the links
+func (k *Keeper) GET(links *Links) string {
+	links.Remove("self")
+	links.Add("zoo", "/zoo", "GET")
+	return "keeper"
+}
+
+func getLinks(t *testing.T, rt Router, method, path string) (*httptest.ResponseRecorder, map[string]Link) {
+	w := doRequest(rt, method, path, "")
+
+	var resp struct {
+		Links map[string]Link `json:"_links"`
+	}
+	if w.Body.Len() > 0 {
+		decodeBody(t, w, &resp)
+	}
+	return w, resp.Links
+}
+
+func TestLinksMember(t *testing.T) {
+	rt, err := NewRouter(Zoo{Animals: Animals{{Name: "Gopher"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a style the links aren't answered
+	_, links := getLinks(t, rt, "GET", "/zoo")
+	if links != nil {
+		t.Fatalf("Expected no links by default, got %v", links)
+	}
+
+	rt.Hypermedia(LinksMember)
+
+	_, links = getLinks(t, rt, "GET", "/zoo")
+	expected := map[string]Link{
+		"self":    {Href: "/zoo", Methods: []string{"GET"}},
+		"animals": {Href: "/zoo/animals", Methods: []string{"GET"}},
+		"keeper":  {Href: "/zoo/keeper", Methods: []string{"GET"}},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected the links %v, got %v", expected, links)
+	}
+
+	// The elements links have their IDs, and the actions their methods
+	_, links = getLinks(t, rt, "POST", "/zoo/animals/3/like")
+	expected = map[string]Link{
+		"self": {Href: "/zoo/animals/3", Methods: []string{"DELETE", "GET"}},
+		"like": {Href: "/zoo/animals/3/like", Methods: []string{"GET", "POST"}},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected the links %v, got %v", expected, links)
+	}
+
+	// Slices don't link their elements
+	_, links = getLinks(t, rt, "GET", "/zoo/animals")
+	if len(links) != 1 || links["self"].Href != "/zoo/animals" {
+		t.Errorf("Expected just the self link, got %v", links)
+	}
+
+	_, links = getLinks(t, rt, "GET", "/zoo/keeper")
+	expected = map[string]Link{
+		"zoo": {Href: "/zoo", Methods: []string{"GET"}},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected the links changed by the method %v, got %v", expected, links)
+	}
+}
+
+func TestLinksHeader(t *testing.T) {
+	rt, err := NewRouter(Zoo{Animals: Animals{{Name: "Gopher"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.Hypermedia(LinksMember)
+	rt.Child("animals").Hypermedia(LinksHeader)
+
+	w, links := getLinks(t, rt, "DELETE", "/zoo/animals/3")
+	header := `</zoo/animals/3>; rel="self"; methods="DELETE GET", </zoo/animals/3/like>; rel="like"; methods="GET POST"`
+	if w.Header().Get("Link") != header {
+		t.Errorf("Expected the Link header %s, got %s", header, w.Header().Get("Link"))
+	}
+	if links != nil {
+		t.Errorf("Expected no _links member, got %v", links)
+	}
+}
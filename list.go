@@ -0,0 +1,441 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//
+// Methods of slice Resources can ask for api.ListParams
+// It is parsed from the query string of the request:
+// ?limit=10&offset=20 or ?limit=10&cursor=<token>
+// ?sort=name,-price sorts by the name and then by the price descending
+// ?price=gt:10&name=contains:go filters the elements, the operator eq is the default
+// The sort and filter fields are validated against the fields of the slice Elem
+//
+
+// Default and maximum number of elements answered by page
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var listParamsType = reflect.TypeOf(ListParams{})
+
+// The parameters to page, filter and sort a list of elements
+type ListParams struct {
+	Limit  int
+	Offset int
+
+	Sort    []SortField
+	Filters []Filter
+
+	// The requested URL, used to build the Links
+	url *url.URL
+
+	// True if the offset was received as a cursor
+	cursor bool
+
+	// The fields of the Elem
+	fields *listFields
+}
+
+// One field to sort the elements by
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// One filter the elements should satisfy
+// Op is one of eq, ne, gt, gte, lt, lte or contains
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+
+	// The value parsed to the type of the field
+	value reflect.Value
+}
+
+// The names of the query parameters reserved to the list parameters
+var listParamNames = map[string]bool{
+	"limit": true, "offset": true, "sort": true, "cursor": true,
+}
+
+var filterOps = map[string]bool{
+	"eq": true, "ne": true, "gt": true, "gte": true, "lt": true, "lte": true, "contains": true,
+}
+
+// Returned when the list parameters are invalid
+// It is answered as 400
+type ListParamsError struct {
+	Param   string
+	Problem string
+}
+
+func (e *ListParamsError) Error() string {
+	return fmt.Sprintf("Invalid list parameter '%s': %s", e.Param, e.Problem)
+}
+
+func (e *ListParamsError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// The fields of the Elem that can be sorted and filtered,
+// indexed by their JSON name and their name, as declared and in lowercase
+type listFields struct {
+	elem  reflect.Type
+	index map[string][]int
+}
+
+func newListFields(elem reflect.Type) *listFields {
+	lf := &listFields{
+		elem:  elem,
+		index: map[string][]int{},
+	}
+
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if !isExportedField(field) || !isComparableKind(field.Type.Kind()) {
+			continue
+		}
+		lf.index[field.Name] = field.Index
+		lf.index[strings.ToLower(field.Name)] = field.Index
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			lf.index[name] = field.Index
+		}
+	}
+
+	return lf
+}
+
+// Return the fields of the Elem of the slice Resource owning the method
+// Just the methods of slice Resources can ask for ListParams
+func listFieldsOf(m reflect.Method) (*listFields, error) {
+	owner := m.Type.In(0)
+	if !isSliceType(owner) || mainElemOfType(owner).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("The method %s of %s asks for api.ListParams, "+
+			"but just the methods of slices of Resources can list elements", m.Name, owner)
+	}
+	return newListFields(mainElemOfType(owner)), nil
+}
+
+// Return true if the method or the constructors of its dependencies asks for the ListParams
+func asksForListParams(m reflect.Method, ds dependencies) bool {
+	types := []reflect.Type{m.Type}
+	for _, d := range ds {
+		if d.constructor != nil {
+			types = append(types, d.constructor.Type)
+		}
+	}
+
+	for _, ft := range types {
+		for i := 0; i < ft.NumIn(); i++ {
+			if ft.In(i) == listParamsType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Parse the list parameters from the query string of the request
+func parseListParams(req *http.Request, lf *listFields) (ListParams, error) {
+	query := req.URL.Query()
+
+	p := ListParams{
+		Limit:   DefaultListLimit,
+		Sort:    []SortField{},
+		Filters: []Filter{},
+		url:     req.URL,
+		fields:  lf,
+	}
+
+	var err error
+	if value := query.Get("limit"); value != "" {
+		p.Limit, err = strconv.Atoi(value)
+		if err != nil || p.Limit < 1 || p.Limit > MaxListLimit {
+			return p, &ListParamsError{"limit", fmt.Sprintf("it should be a number from 1 to %d", MaxListLimit)}
+		}
+	}
+
+	if value := query.Get("offset"); value != "" {
+		p.Offset, err = strconv.Atoi(value)
+		if err != nil || p.Offset < 0 {
+			return p, &ListParamsError{"offset", "it should be a positive number"}
+		}
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		p.Offset, err = decodeCursor(value)
+		if err != nil {
+			return p, &ListParamsError{"cursor", "it isn't a valid cursor"}
+		}
+		p.cursor = true
+	}
+
+	if value := query.Get("sort"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			sf := SortField{Field: strings.TrimSpace(name)}
+			if strings.HasPrefix(sf.Field, "-") {
+				sf.Field, sf.Desc = sf.Field[1:], true
+			}
+			if _, exist := lf.index[sf.Field]; !exist {
+				return p, &ListParamsError{"sort", fmt.Sprintf("%s has no field '%s'", lf.elem, sf.Field)}
+			}
+			p.Sort = append(p.Sort, sf)
+		}
+	}
+
+	// The other parameters are filters, named as the fields
+	// The names of the list parameters are never filters,
+	// even when the Elem has fields with these names
+	names := make([]string, 0, len(query))
+	for name := range query {
+		if !listParamNames[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		index, exist := lf.index[name]
+		if !exist {
+			return p, &ListParamsError{name, fmt.Sprintf("%s has no field '%s'", lf.elem, name)}
+		}
+		for _, value := range query[name] {
+			f, err := newFilter(name, value, lf.elem.FieldByIndex(index).Type)
+			if err != nil {
+				return p, err
+			}
+			p.Filters = append(p.Filters, f)
+		}
+	}
+
+	return p, nil
+}
+
+// Parse a filter like gt:10, or just 10 to compare with eq
+func newFilter(name, value string, t reflect.Type) (Filter, error) {
+	f := Filter{Field: name, Op: "eq", Value: value}
+
+	if i := strings.Index(value, ":"); i > 0 && filterOps[value[:i]] {
+		f.Op, f.Value = value[:i], value[i+1:]
+	}
+
+	if f.Op == "contains" && t.Kind() != reflect.String {
+		return f, &ListParamsError{name, "contains can filter just text fields"}
+	}
+
+	f.value = reflect.New(t).Elem()
+	err := setConfigScalar(f.value, f.Value)
+	if err != nil {
+		return f, &ListParamsError{name, fmt.Sprintf("'%s' isn't a valid %s", f.Value, t)}
+	}
+
+	return f, nil
+}
+
+// Filter, sort and page the slice of elements
+// It receives a slice, or a Ptr to one, of the Elem or of Ptrs to it
+// and returns a new slice of the same type with the elements of the page
+// and the number of elements that satisfies the filters
+func (p ListParams) Apply(slice interface{}) (interface{}, int) {
+	value := elemOfValue(reflect.ValueOf(slice))
+
+	matched := reflect.MakeSlice(value.Type(), 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		if p.matches(value.Index(i)) {
+			matched = reflect.Append(matched, value.Index(i))
+		}
+	}
+
+	sort.SliceStable(matched.Interface(), func(i, j int) bool {
+		return p.less(matched.Index(i), matched.Index(j))
+	})
+
+	total := matched.Len()
+	start, end := p.Offset, p.Offset+p.Limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	page := reflect.MakeSlice(value.Type(), end-start, end-start)
+	reflect.Copy(page, matched.Slice(start, end))
+
+	return page.Interface(), total
+}
+
+// Return the field of the element
+func (p ListParams) field(elem reflect.Value, name string) reflect.Value {
+	return elemOfValue(elem).FieldByIndex(p.fields.index[name])
+}
+
+// Return true if the element satisfies all the filters
+func (p ListParams) matches(elem reflect.Value) bool {
+	for _, f := range p.Filters {
+		v := p.field(elem, f.Field)
+
+		if f.Op == "contains" {
+			if !strings.Contains(v.String(), f.value.String()) {
+				return false
+			}
+			continue
+		}
+
+		if !satisfies(compareValues(v, f.value), f.Op) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return true if the result of the comparison satisfies the operator
+func satisfies(c int, op string) bool {
+	switch op {
+	case "eq":
+		return c == 0
+	case "ne":
+		return c != 0
+	case "gt":
+		return c > 0
+	case "gte":
+		return c >= 0
+	case "lt":
+		return c < 0
+	case "lte":
+		return c <= 0
+	}
+	return false
+}
+
+// Return true if the element a should come before b
+func (p ListParams) less(a, b reflect.Value) bool {
+	for _, s := range p.Sort {
+		c := compareValues(p.field(a, s.Field), p.field(b, s.Field))
+		if c == 0 {
+			continue
+		}
+		return c < 0 != s.Desc
+	}
+	return false
+}
+
+var pageType = reflect.TypeOf((*Page)(nil))
+
+// The page of elements answered, with the number of elements
+// that satisfies the filters
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// Return the page of items and write the Link header,
+// with the first, prev and next pages, and the X-Total-Count header
+// The total is the number of elements that satisfies the filters
+func (p ListParams) Page(w http.ResponseWriter, items interface{}, total int) *Page {
+	links := []string{p.link(0, "first")}
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, p.link(prev, "prev"))
+	}
+	if p.Offset+p.Limit < total {
+		links = append(links, p.link(p.Offset+p.Limit, "next"))
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	return &Page{
+		Items:  items,
+		Total:  total,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	}
+}
+
+// Return the Link to the page starting at the offset
+// It keeps the filters and the sort of the requested URL
+func (p ListParams) link(offset int, rel string) string {
+	if p.url == nil {
+		return ""
+	}
+
+	query := p.url.Query()
+	query.Del("offset")
+	query.Del("cursor")
+	query.Set("limit", strconv.Itoa(p.Limit))
+	if p.cursor {
+		query.Set("cursor", encodeCursor(offset))
+	} else {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	u := *p.url
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf("<%s>; rel=\"%s\"", u.RequestURI(), rel)
+}
+
+// Cursors are opaque tokens of the offsets
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), "offset:") {
+		return 0, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(b), "offset:"))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	return offset, nil
+}
+
+// Return true if the fields of the kind can be sorted and filtered
+func isComparableKind(k reflect.Kind) bool {
+	return k == reflect.String || k == reflect.Bool || isNumberKind(k)
+}
+
+// Compare two values of the same comparable kind
+// It returns -1 if a is less than b, 1 if greater and 0 if equal
+func compareValues(a, b reflect.Value) int {
+	switch {
+	case a.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case a.Kind() == reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0
+		}
+		if !a.Bool() {
+			return -1
+		}
+		return 1
+	}
+
+	x, y := sizeOf(a), sizeOf(b)
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
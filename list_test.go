@@ -0,0 +1,143 @@
+// This package tests the paging, filtering and sorting of lists
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Catalog struct {
+	Books Books
+}
+
+type Books []Book
+
+type Book struct {
+	Title string `json:"title"`
+	Pages int    `json:"pages"`
+	Draft bool   `json:"draft"`
+
+	// Named as a list parameter, so it can't be filtered
+	Sort int `json:"sort"`
+}
+
+func (b Books) GET(p ListParams, w http.ResponseWriter) *Page {
+	items, total := p.Apply(b)
+	return p.Page(w, items, total)
+}
+
+func (b *Book) GET() *Book {
+	return b
+}
+
+type Shelves struct{}
+
+// Just slices can list elements
+func (s *Shelves) GET(p ListParams) string {
+	return "shelves"
+}
+
+var catalog = Catalog{Books: Books{
+	{Title: "Go", Pages: 300},
+	{Title: "Gophers", Pages: 120},
+	{Title: "Rust", Pages: 500},
+	{Title: "Draft", Pages: 50, Draft: true},
+	{Title: "Goroutines", Pages: 220},
+}}
+
+func getBooks(t *testing.T, rt Router, query string) (*httptest.ResponseRecorder, Books, int) {
+	w := doRequest(rt, "GET", "/catalog/books"+query, "")
+
+	var resp struct {
+		Page struct {
+			Items Books `json:"items"`
+			Total int   `json:"total"`
+		}
+	}
+	if w.Code == http.StatusOK {
+		decodeBody(t, w, &resp)
+	}
+	return w, resp.Page.Items, resp.Page.Total
+}
+
+func titles(books Books) string {
+	names := make([]string, len(books))
+	for i, b := range books {
+		names[i] = b.Title
+	}
+	return strings.Join(names, ",")
+}
+
+func TestListParams(t *testing.T) {
+	rt, err := NewRouter(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		query  string
+		titles string
+		total  int
+	}{
+		{"", "Go,Gophers,Rust,Draft,Goroutines", 5},
+		{"?limit=2&offset=1", "Gophers,Rust", 5},
+		{"?sort=-pages", "Rust,Go,Goroutines,Gophers,Draft", 5},
+		{"?title=contains:Go&sort=title", "Go,Gophers,Goroutines", 3},
+		{"?pages=gte:200&draft=false&sort=pages", "Goroutines,Go,Rust", 3},
+		{"?Pages=lt:100", "Draft", 1},
+	}
+	for _, c := range cases {
+		_, books, total := getBooks(t, rt, c.query)
+		if titles(books) != c.titles || total != c.total {
+			t.Errorf("Expected %s of %d for %q, got %s of %d", c.titles, c.total, c.query, titles(books), total)
+		}
+	}
+
+	// The parameters are validated against the Elem fields
+	for _, query := range []string{"?sort=author", "?pages=gt:many", "?limit=1000", "?pages=contains:1", "?cursor=x", "?titel=go"} {
+		w, _, _ := getBooks(t, rt, query)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestListLinks(t *testing.T) {
+	rt, err := NewRouter(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, _, _ := getBooks(t, rt, "?limit=2&offset=2&sort=title")
+	link := w.Header().Get("Link")
+	for _, expected := range []string{
+		`</catalog/books?limit=2&offset=0&sort=title>; rel="first"`,
+		`</catalog/books?limit=2&offset=0&sort=title>; rel="prev"`,
+		`</catalog/books?limit=2&offset=4&sort=title>; rel="next"`,
+	} {
+		if !strings.Contains(link, expected) {
+			t.Errorf("Expected the Link %s, got %s", expected, link)
+		}
+	}
+	if w.Header().Get("X-Total-Count") != "5" {
+		t.Errorf("Expected the total count 5, got %q", w.Header().Get("X-Total-Count"))
+	}
+
+	// Requests with cursors are linked with cursors
+	w, books, _ := getBooks(t, rt, "?limit=2&cursor="+encodeCursor(4))
+	if titles(books) != "Goroutines" || strings.Contains(w.Header().Get("Link"), "next") {
+		t.Errorf("Expected the last page, got %s %s", titles(books), w.Header().Get("Link"))
+	}
+	if !strings.Contains(w.Header().Get("Link"), "cursor="+encodeCursor(2)) {
+		t.Errorf("Expected the prev Link with a cursor, got %s", w.Header().Get("Link"))
+	}
+}
+
+func TestListParamsOutOfSlice(t *testing.T) {
+	_, err := NewRouter(Shelves{})
+	if err == nil || !strings.Contains(err.Error(), "ListParams") {
+		t.Errorf("Expected an error asking for ListParams out of a slice, got %v", err)
+	}
+}
@@ -12,6 +12,23 @@ type method struct {
 	// that could be satisfied by a single dependency
 	dependencies dependencies
 	outName      []string
+
+	// The precompiled plan used to inject
+	// the dependencies when answering the requests
+	plan *plan
+
+	// True if it was generated for a Resource backed by a Store
+	// Generated methods answer the errors they output with the error Status Code
+	generated bool
+
+	// Status Code sent when the method succeeds, 200 if not defined
+	status int
+
+	// The fields of the listed Elem, if the method asks for the ListParams
+	list *listFields
+
+	// How its responses can be cached, nil if no policy was declared
+	cache *CachePolicy
 }
 
 func newMethod(m reflect.Method, r *resource) (*method, error) {
@@ -29,6 +46,19 @@ func newMethod(m reflect.Method, r *resource) (*method, error) {
 		outName:      make([]string, m.Type.NumOut()),
 	}
 
+	// Methods listing elements are validated against the Elem fields
+	if asksForListParams(m, ds) {
+		h.list, err = listFieldsOf(m)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	h.cache, err = r.cachePolicy(m.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	// Caching the Output Resources name
 	for i := 0; i < m.Type.NumOut(); i++ {
 		h.outName[i] = elemOfType(m.Type.Out(i)).Name()
@@ -38,5 +68,8 @@ func newMethod(m reflect.Method, r *resource) (*method, error) {
 }
 
 func (h *method) String() string {
+	if h.generated {
+		return fmt.Sprintf("[%s] %s (generated)", h.method.Name, h.method.Type)
+	}
 	return fmt.Sprintf("[%s] %s", h.method.Name, h.method.Type)
 }
@@ -0,0 +1,68 @@
+package api
+
+import (
+	gocontext "context"
+	"net/http"
+)
+
+// Standard net/http middleware
+// It receives the next Handler in the chain and returns a new one wrapping it
+type Middleware func(http.Handler) http.Handler
+
+// Metadata of the Route that matched a request
+// It is stored in the request context before the middleware chain runs,
+// so logging, metrics and auth middlewares can use the route template
+// insted of the raw URL
+type RouteInfo struct {
+	// The route template, with the IDs replaced by placeholders
+	// ex: /api/gophers/:gopher/message
+	Template string
+
+	// The HTTP method requested
+	// ex: GET
+	HTTPMethod string
+
+	// The name of the mapped method that will answer the request
+	// ex: GETMessage
+	Method string
+
+	// The Resource type that owns the mapped method
+	// ex: *api.Gopher
+	Resource string
+}
+
+type routeInfoKey struct{}
+
+// Return the metadata of the Route that matched this request
+// It returns nil if the request wasn't routed by a Router
+func RouteOf(req *http.Request) *RouteInfo {
+	return RouteFromContext(req.Context())
+}
+
+// Return the metadata of the Route stored in the given context
+// It returns nil if there is no Route stored in it
+func RouteFromContext(ctx gocontext.Context) *RouteInfo {
+	info, _ := ctx.Value(routeInfoKey{}).(*RouteInfo)
+	return info
+}
+
+// Store the Route metadata in the request context
+func withRouteInfo(req *http.Request, info *RouteInfo) *http.Request {
+	return req.WithContext(gocontext.WithValue(req.Context(), routeInfoKey{}, info))
+}
+
+// Attach middlewares to this Route
+// They will run after the route resolution for every request
+// that matches this Route or any of its children
+func (ro *route) Use(middlewares ...Middleware) {
+	ro.middlewares = append(ro.middlewares, middlewares...)
+}
+
+// Wrap the Handler with the middlewares in the given order,
+// so the first middleware will be the first one to receive the request
+func chain(h http.Handler, middlewares []Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
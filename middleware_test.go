@@ -0,0 +1,61 @@
+// This package tests the middlewares attached to the Router
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Testing the middleware chain
+// The global middleware should run for every request
+// and the one attached to the Gophers subtree only for its children
+func TestMiddleware(t *testing.T) {
+	rt, err := NewRouter(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name+" "+RouteOf(req).Template)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	rt.Use(record("global"))
+	rt.Child("gophers").Use(record("gophers"))
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/api/gophers/2/message", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+	errorTest(w, t)
+
+	if len(calls) != 2 ||
+		calls[0] != "global /api/gophers/:gopher/message" ||
+		calls[1] != "gophers /api/gophers/:gopher/message" {
+		t.Fatalf("Middlewares called wrong: %q", calls)
+	}
+
+	calls = nil
+
+	w = httptest.NewRecorder()
+	req, err = http.NewRequest("GET", "/api/version", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+	errorTest(w, t)
+
+	if len(calls) != 1 || calls[0] != "global /api/version" {
+		t.Fatalf("Middlewares called wrong: %q", calls)
+	}
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"reflect"
+)
+
+// The precompiled injection plan of a mapped method
+// Everything that can be decided before receiving requests is decided here,
+// so the requests handling just executes the plan
+type plan struct {
+	// Number of slots the request needs to store its values
+	// The slot 0 stores the http.ResponseWriter and the slot 1 the *http.Request
+	slots int
+
+	// Constructors to call, in the order they should be called
+	steps []*step
+
+	// Arguments of the mapped method
+	args []argument
+
+	// The fields of the listed Elem, if some argument is the ListParams
+	list *listFields
+
+	// True if some argument is the *Links
+	links bool
+}
+
+// Slots always present in the context
+const (
+	writerSlot = iota
+	requestSlot
+	firstStepSlot
+)
+
+// One dependency to be constructed
+type step struct {
+	dependency *dependency
+
+	// The slot that stores the constructed value
+	slot int
+
+	// Arguments of the constructor, but the first one,
+	// that is the dependency itself
+	args []argument
+
+	// Indexes of the steps that should be executed before this one
+	// Used to construct the dependencies concurrently
+	requires []int
+
+	// True if a failure of its constructor stops the request
+	stop bool
+
+	// Specific error types asked after this step
+	// A failure matching some of them doesn't stop the request
+	handles []reflect.Type
+}
+
+// Where the value of an argument comes from
+type argumentKind int
+
+const (
+	slotArgument argumentKind = iota
+	errorArgument
+	errorSliceArgument
+	typedErrorArgument
+	contextArgument
+	idArgument
+	listArgument
+	linksArgument
+)
+
+type argument struct {
+	kind argumentKind
+
+	// The type asked by the method
+	t reflect.Type
+
+	// The slot that stores the value, for slot arguments
+	slot int
+
+	// True if the method asks for the Elem insted of the Ptr stored in the slot,
+	// like the Structs, the Slices and the provided values
+	elem bool
+
+	// The type that is asking for the ID, for ID arguments
+	requester reflect.Type
+}
+
+// Compile the injection plan of the method
+// It should be called after checking the Circular Dependency
+func newPlan(m *method) *plan {
+	pl := &planner{
+		method: m,
+		plan:   &plan{slots: firstStepSlot, steps: []*step{}},
+		slotOf: map[*dependency]int{},
+	}
+	pl.plan.args = pl.arguments(&m.method, 0)
+	pl.stops()
+	return pl.plan
+}
+
+type planner struct {
+	method *method
+	plan   *plan
+
+	// The slot of each request scoped or singleton dependency already planned
+	slotOf map[*dependency]int
+}
+
+// Plan the arguments of the method, starting from the input first
+func (pl *planner) arguments(m *reflect.Method, first int) []argument {
+	requester := m.Type.In(0)
+	args := make([]argument, 0, m.Type.NumIn())
+	for i := first; i < m.Type.NumIn(); i++ {
+		args = append(args, pl.argument(m.Type.In(i), requester))
+	}
+	return args
+}
+
+// Plan where the value of the required type comes from
+func (pl *planner) argument(t reflect.Type, requester reflect.Type) argument {
+	a := argument{kind: slotArgument, t: t}
+
+	switch {
+	case t == errorType:
+		a.kind = errorArgument
+	case t == errorSliceType:
+		a.kind = errorSliceArgument
+	case isTypedErrorType(t):
+		a.kind = typedErrorArgument
+	case t == contextType:
+		a.kind = contextArgument
+	case t == listParamsType:
+		a.kind = listArgument
+		pl.plan.list = pl.method.list
+	case t == linksPtrType:
+		a.kind = linksArgument
+		pl.plan.links = true
+	case t == idInterfaceType:
+		a.kind = idArgument
+		a.requester = requester
+	case t.AssignableTo(responseWriterType):
+		a.slot = writerSlot
+	case t.AssignableTo(requestPtrType):
+		a.slot = requestSlot
+	default:
+		d := pl.method.dependencies[t]
+		a.slot = pl.dependency(d)
+		a.elem = isElemOf(t, d.value.Type())
+	}
+
+	return a
+}
+
+// Plan the construction of the dependency after its own dependencies
+// and return the slot that will store it
+// Transients get a new step for each injection point
+func (pl *planner) dependency(d *dependency) int {
+	if d.scope != TransientScope {
+		if slot, exist := pl.slotOf[d]; exist {
+			return slot
+		}
+	}
+
+	s := &step{dependency: d, args: []argument{}}
+
+	// Singletons are constructed just once, following their own plan
+	if d.scope == SingletonScope {
+		planSingleton(pl.method, d)
+	} else if d.constructor != nil {
+		s.args = pl.arguments(d.constructor, 1)
+	}
+
+	s.slot = pl.plan.slots
+	pl.plan.slots++
+
+	s.requires = pl.requirements(s)
+	pl.plan.steps = append(pl.plan.steps, s)
+
+	if d.scope != TransientScope {
+		pl.slotOf[d] = s.slot
+	}
+
+	return s.slot
+}
+
+// Compile the plan to construct the singleton, out of the requests plans
+// Its arguments are the inputs of the constructor, if it has one, but the first one
+// Test doubles are never constructed, so they don't need it
+func planSingleton(m *method, d *dependency) {
+	if d.override || d.plan != nil {
+		return
+	}
+
+	pl := &planner{
+		method: m,
+		plan:   &plan{slots: firstStepSlot, steps: []*step{}},
+		slotOf: map[*dependency]int{},
+	}
+	if d.constructor != nil {
+		pl.plan.args = pl.arguments(d.constructor, 1)
+	}
+	pl.stops()
+	d.plan = pl.plan
+}
+
+// Return the steps this step depends on
+// If it asks for the errors, it depends on all previous steps
+// to keep the errors order
+func (pl *planner) requirements(s *step) []int {
+	requires := []int{}
+	for _, a := range s.args {
+		if a.kind == errorArgument || a.kind == errorSliceArgument || a.kind == typedErrorArgument {
+			requires = requires[:0]
+			for i := range pl.plan.steps {
+				requires = append(requires, i)
+			}
+			return requires
+		}
+		if a.kind == slotArgument && a.slot >= firstStepSlot {
+			requires = append(requires, a.slot-firstStepSlot)
+		}
+	}
+	return requires
+}
+
+// Execute one step of the plan, storing the dependency in its slot
+// If its constructor fails and the step stops the request,
+// the error is stored as the context failure
+func (c *context) runStep(s *step) {
+	var v reflect.Value
+	var ok bool
+
+	previous := len(c.errors)
+
+	if s.dependency.scope == SingletonScope {
+		v, ok = c.singletonValue(s.dependency)
+	} else {
+		v, ok = c.constructWith(s.dependency, func(value reflect.Value) []reflect.Value {
+			return c.stepInputs(s, value)
+		})
+		c.track(s.dependency, v)
+	}
+
+	c.slots[s.slot] = v
+
+	if !ok && s.stop && len(c.errors) > previous {
+		err := c.errors[len(c.errors)-1].Interface().(error)
+		if !matchesSomeType(err, s.handles) {
+			c.failure = err
+		}
+	}
+}
+
+// Return the inputs of the step constructor
+// The first input is the dependency itself, that is being constructed
+func (c *context) stepInputs(s *step, value reflect.Value) []reflect.Value {
+	inputs := make([]reflect.Value, len(s.args)+1)
+
+	// The constructor could be attached to the Elem insted of the Ptr
+	if s.dependency.constructor.Type.In(0).Kind() != reflect.Ptr {
+		value = value.Elem()
+	}
+	inputs[0] = value
+
+	for i := range s.args {
+		inputs[i+1] = c.argumentValue(&s.args[i])
+	}
+	return inputs
+}
+
+// Return the inputs of the mapped method
+func (c *context) planInputs(args []argument) []reflect.Value {
+	inputs := make([]reflect.Value, len(args))
+	for i := range args {
+		inputs[i] = c.argumentValue(&args[i])
+	}
+	return inputs
+}
+
+// Return the value of the argument
+func (c *context) argumentValue(a *argument) reflect.Value {
+	switch a.kind {
+	case errorArgument:
+		return c.errorValue()
+	case errorSliceArgument:
+		return c.errorSliceValue()
+	case typedErrorArgument:
+		return c.typedErrorValue(a.t)
+	case contextArgument:
+		return reflect.ValueOf(&c.ctx).Elem()
+	case idArgument:
+		return c.idValue(a.requester)
+	case listArgument:
+		return reflect.ValueOf(c.list)
+	case linksArgument:
+		return reflect.ValueOf(c.links)
+	}
+
+	v := c.slots[a.slot]
+
+	// If it is requiring the Elem itself or the Slice itself
+	if a.elem {
+		// It is requiring the Elem of a nil Ptr?
+		// Ok, give it an empty Elem of that Type
+		if v.IsNil() {
+			return reflect.New(a.t).Elem()
+		}
+		return v.Elem()
+	}
+
+	return v
+}
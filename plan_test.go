@@ -0,0 +1,39 @@
+// This package benchmarks the requests handling
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func benchmarkRequest(b *testing.B, method, uri string) {
+	rt, err := NewRouter(api)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rt.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkGopherGet(b *testing.B) {
+	benchmarkRequest(b, "GET", "/api/gophers/1")
+}
+
+func BenchmarkGopherMessage(b *testing.B) {
+	benchmarkRequest(b, "GET", "/api/gophers/2/message")
+}
+
+func BenchmarkInterfaceInjection(b *testing.B) {
+	benchmarkRequest(b, "GET", "/api/dogbark")
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A function that provides the values of its output type,
+// for types that can't be Resources, like *sql.DB or func() time.Time
+// Its form is func(deps...) T or func(deps...) (T, error),
+// and its inputs are injected like the inputs of the New methods
+// The provided values are matched by the exact output type
+type Provider struct {
+	Func interface{}
+
+	// The lifetime of the provided values, a new one for each request by default
+	Scope Scope
+}
+
+// A provider validated, ready to be used as a constructor
+type provider struct {
+	t     reflect.Type
+	scope Scope
+
+	// The provider function wrapped in the form of a New method,
+	// the first input is the pointer where the value is stored
+	constructor reflect.Method
+}
+
+// Validate the providers of the config, indexed by its output type
+func newProviders(config *Config) (map[reflect.Type]*provider, error) {
+	providers := map[reflect.Type]*provider{}
+	if config == nil {
+		return providers, nil
+	}
+
+	for _, p := range config.Providers {
+		pr, err := newProvider(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exist := providers[pr.t]; exist {
+			return nil, fmt.Errorf("Two providers provide the type %s", pr.t)
+		}
+		providers[pr.t] = pr
+	}
+
+	return providers, nil
+}
+
+func newProvider(p Provider) (*provider, error) {
+	fn := reflect.ValueOf(p.Func)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return nil, fmt.Errorf("The provider %T should be a function", p.Func)
+	}
+
+	ft := fn.Type()
+	if ft.IsVariadic() || ft.NumOut() == 0 || ft.NumOut() > 2 ||
+		ft.Out(0) == errorType || ft.NumOut() == 2 && ft.Out(1) != errorType {
+		return nil, fmt.Errorf("The provider %s should be in the form func(deps...) (T, error)", ft)
+	}
+
+	t := ft.Out(0)
+	if isContextType(t) {
+		return nil, fmt.Errorf("The provider %s can't provide the type %s", ft, t)
+	}
+
+	// The value is stored in a pointer, like the Resources
+	in := []reflect.Type{reflect.PtrTo(t)}
+	for i := 0; i < ft.NumIn(); i++ {
+		in = append(in, ft.In(i))
+	}
+	out := []reflect.Type{}
+	if ft.NumOut() == 2 {
+		out = append(out, errorType)
+	}
+
+	wrapperType := reflect.FuncOf(in, out, false)
+	wrapper := reflect.MakeFunc(wrapperType, func(args []reflect.Value) []reflect.Value {
+		results := fn.Call(args[1:])
+		args[0].Elem().Set(results[0])
+		return results[1:]
+	})
+
+	return &provider{
+		t:     t,
+		scope: p.Scope,
+		constructor: reflect.Method{
+			Name: "Provide",
+			Type: wrapperType,
+			Func: wrapper,
+		},
+	}, nil
+}
+
+// Creates the dependency constructed by the provider
+// It stores a pointer to the provided value
+func newProvidedDependency(p *provider, r *resource) (*dependency, error) {
+	constructor := p.constructor
+
+	d := &dependency{
+		value:       reflect.New(p.t),
+		constructor: &constructor,
+		scope:       p.scope,
+		provided:    true,
+	}
+
+	err := d.setScope(nil, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Return the value injected for the dependency
+// Provided values are stored in a pointer, but injected by themselves
+func (d *dependency) injected(v reflect.Value) reflect.Value {
+	if d.provided {
+		return v.Elem()
+	}
+	return v
+}
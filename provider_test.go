@@ -0,0 +1,103 @@
+// This package tests the provider functions
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var auditLog bytes.Buffer
+
+type Token string
+
+type Kiosk struct{}
+
+func (k *Kiosk) GET(logger *log.Logger, now func() time.Time, token Token) string {
+	logger.Print("served " + string(token))
+	return now().Format("15:04")
+}
+
+func kioskProviders() []Provider {
+	return []Provider{
+		{Func: func() io.Writer { return &auditLog }, Scope: SingletonScope},
+		{Func: func(w io.Writer) *log.Logger { return log.New(w, "", 0) }, Scope: SingletonScope},
+		{Func: func() func() time.Time {
+			return func() time.Time { return time.Date(2020, 1, 1, 9, 30, 0, 0, time.UTC) }
+		}},
+		{Func: func(req *http.Request) (Token, error) {
+			token := req.Header.Get("Token")
+			if token == "" {
+				return "", errors.New("missing token")
+			}
+			return Token(token), nil
+		}},
+	}
+}
+
+func serveKiosk(t *testing.T, rt Router, token string) (int, map[string]string) {
+	w := doRequest(rt, "GET", "/kiosk", "", "Token", token)
+
+	var response map[string]string
+	decodeBody(t, w, &response)
+	return w.Code, response
+}
+
+func TestProviders(t *testing.T) {
+	// The log is shared by the providers, it starts empty in each run
+	auditLog.Reset()
+
+	rt, err := NewRouterWithConfig(Kiosk{}, &Config{Providers: kioskProviders()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, response := serveKiosk(t, rt, "abc")
+	if code != http.StatusOK || response["string"] != "09:30" {
+		t.Errorf("Expected the provided time, got %d %v", code, response)
+	}
+	if auditLog.String() != "served abc\n" {
+		t.Errorf("Expected the provided logger to be used, got %q", auditLog.String())
+	}
+
+	code, response = serveKiosk(t, rt, "")
+	if code != http.StatusInternalServerError || response["error"] != "missing token" {
+		t.Errorf("Expected the provider failure, got %d %v", code, response)
+	}
+}
+
+func TestProviderCircularDependency(t *testing.T) {
+	_, err := NewRouterWithConfig(Kiosk{}, &Config{Providers: append(kioskProviders(),
+		Provider{Func: func(now func() time.Time) *log.Logger { return nil }},
+	)})
+	if err == nil || !strings.Contains(err.Error(), "Two providers") {
+		t.Errorf("Expected the duplicated provider error, got %v", err)
+	}
+
+	providers := kioskProviders()
+	providers[1] = Provider{Func: func(token Token) *log.Logger { return nil }}
+	providers[3] = Provider{Func: func(logger *log.Logger) Token { return "" }}
+	_, err = NewRouterWithConfig(Kiosk{}, &Config{Providers: providers})
+	if err == nil || !strings.Contains(err.Error(), "depends on") {
+		t.Errorf("Expected the circular dependency error, got %v", err)
+	}
+}
+
+func TestInvalidProvider(t *testing.T) {
+	for _, fn := range []interface{}{
+		"not a function",
+		func() {},
+		func() error { return nil },
+		func() (int, string) { return 0, "" },
+	} {
+		_, err := NewRouterWithConfig(Kiosk{}, &Config{Providers: []Provider{{Func: fn}}})
+		if err == nil {
+			t.Errorf("Expected the provider %T to be rejected", fn)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//
+// When an Interface is implemented by more than one Resource of the same level
+// of the tree, the Resource to inject should be chosen by a qualifier
+// The Resource field receives the tag `qualifier:"primary"` and the methods
+// ask for a wrapper Struct with a field of the Interface with the same tag:
+//
+//	type PrimaryDB struct {
+//		DB `qualifier:"primary"`
+//	}
+//
+
+// Return the qualifier of the Resource, defined in the tag of its field
+func (r *resource) qualifier() string {
+	return r.tag.Get("qualifier")
+}
+
+// Return the Resources of this level of the tree that satisfy the Interface
+func (r *resource) implementations(t reflect.Type) []*resource {
+	found := []*resource{}
+	for _, child := range r.children {
+		if child.isType(t) {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+// Return the error reporting all the Resources that satisfy the Interface
+func ambiguousError(t reflect.Type, candidates []*resource) error {
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = strings.TrimSpace(c.String())
+		if q := c.qualifier(); q != "" {
+			names[i] += fmt.Sprintf(" qualifier:%q", q)
+		}
+	}
+	return fmt.Errorf("The Interface %s is ambiguous, it is implemented by %d Resources: %s. "+
+		"Tag them with `qualifier:\"name\"` and ask for a Struct with a field of the Interface with the same tag",
+		t, len(candidates), strings.Join(names, ", "))
+}
+
+// Return an error if the Interface asked by the methods of this Resource is ambiguous
+// It is checked for each Interface asked while the Routes are created,
+// unless a test double or a provider replaces it
+func (r *resource) checkAmbiguity(t reflect.Type) error {
+	if t.Kind() != reflect.Interface || isContextType(t) {
+		return nil
+	}
+	if _, exist := r.root().overrides[overrideKey(t)]; exist {
+		return nil
+	}
+	if _, exist := r.root().providers[t]; exist {
+		return nil
+	}
+	_, err := r.resourceOf(t)
+	return err
+}
+
+// Return the Resource that satisfies the Interface and has this qualifier
+// It searches in this Resource children or in its parents children recursively
+func (r *resource) qualifiedResourceOf(t reflect.Type, qualifier string) (*resource, error) {
+	for res := r; res != nil; res = res.parent {
+		for _, child := range res.implementations(t) {
+			if child.qualifier() == qualifier {
+				return child, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("Not found any Resource with the qualifier '%s' "+
+		"that implements the Interface %s in the Resource tree %s", qualifier, t, r)
+}
+
+// Return true if the Struct has fields tagged with a qualifier
+func isQualifiedWrapper(t reflect.Type) bool {
+	t = elemOfType(t)
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, exist := t.Field(i).Tag.Lookup("qualifier"); exist {
+			return true
+		}
+	}
+	return false
+}
+
+// Creates the constructor of the wrapper Struct, in the form of a New method,
+// that fills its qualified fields with the qualified Resources
+// The qualified Resources are asked by their own types, so they are constructed
+// like any other dependency of the request
+func newWrapperConstructor(t reflect.Type, r *resource) (*reflect.Method, error) {
+	t = elemOfType(t)
+
+	in := []reflect.Type{reflect.PtrTo(t)}
+	fields := []int{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		qualifier, exist := field.Tag.Lookup("qualifier")
+		if !exist {
+			continue
+		}
+		if field.Type.Kind() != reflect.Interface || field.PkgPath != "" {
+			return nil, fmt.Errorf("The qualified field %s of %s should be an exported Interface", field.Name, t)
+		}
+
+		res, err := r.qualifiedResourceOf(field.Type, qualifier)
+		if err != nil {
+			return nil, err
+		}
+
+		// The qualified Resource is found by its own type,
+		// so another Resource of the same type can't be nearer
+		nearest, err := r.resourceOf(res.value.Type())
+		if err != nil {
+			return nil, err
+		}
+		if nearest != res {
+			return nil, fmt.Errorf("The qualified Resource %s is hidden by the Resource %s of the same type",
+				strings.TrimSpace(res.String()), strings.TrimSpace(nearest.String()))
+		}
+
+		in = append(in, res.value.Type())
+		fields = append(fields, i)
+	}
+
+	constructorType := reflect.FuncOf(in, []reflect.Type{}, false)
+	constructor := reflect.MakeFunc(constructorType, func(args []reflect.Value) []reflect.Value {
+		for i, field := range fields {
+			args[0].Elem().Field(field).Set(args[i+1])
+		}
+		return nil
+	})
+
+	return &reflect.Method{
+		Name: "Qualify",
+		Type: constructorType,
+		Func: constructor,
+	}, nil
+}
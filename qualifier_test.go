@@ -0,0 +1,121 @@
+// This package tests the ambiguous Interfaces and the qualifiers
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Beagle struct{}
+
+func (b *Beagle) Bark() string {
+	return "Au Au"
+}
+
+type Kennel struct {
+	Small  Maltese `qualifier:"small"`
+	Big    Beagle  `qualifier:"big"`
+	Walker Walker
+}
+
+type Walker struct{}
+
+type BigDog struct {
+	Doger `qualifier:"big"`
+}
+
+type BothDogs struct {
+	Small Doger `qualifier:"small"`
+	Big   Doger `qualifier:"big"`
+}
+
+func (w *Walker) GET(dog BigDog) string {
+	return dog.Bark()
+}
+
+func (w *Walker) GETBoth(dogs *BothDogs) string {
+	return dogs.Small.Bark() + " " + dogs.Big.Bark()
+}
+
+type Shelter struct {
+	Small Maltese
+	Big   Beagle
+	Owner Owner
+}
+
+type Owner struct{}
+
+func (o *Owner) GET(dog Doger) string {
+	return dog.Bark()
+}
+
+// The Beagle asked first satisfies the Interface,
+// but the Interface is still ambiguous
+type Salon struct {
+	Small   Maltese
+	Big     Beagle
+	Groomer Groomer
+}
+
+type Groomer struct{}
+
+func (g *Groomer) GET(b *Beagle, dog Doger) string {
+	return dog.Bark()
+}
+
+func TestAmbiguousInterface(t *testing.T) {
+	_, err := NewRouter(Shelter{})
+	if err == nil {
+		t.Fatal("Expected the ambiguous Interface to be reported")
+	}
+	for _, candidate := range []string{"[small] *api.Maltese", "[big] *api.Beagle"} {
+		if !strings.Contains(err.Error(), candidate) {
+			t.Errorf("Expected the candidate %s in the error %s", candidate, err)
+		}
+	}
+
+	_, err = NewRouter(Salon{})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected the ambiguous Interface to be reported after another dependency, got %v", err)
+	}
+}
+
+func TestQualifiers(t *testing.T) {
+	rt, err := NewRouter(Kennel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"/kennel/walker":      "Au Au",
+		"/kennel/walker/both": (&Maltese{}).Bark() + " Au Au",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		rt.ServeHTTP(w, req)
+
+		var resp StringResp
+		err = json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.String != expected {
+			t.Errorf("Expected %q from %s, got %q", expected, path, resp.String)
+		}
+	}
+}
+
+type Stray struct {
+	Walker Walker
+	Dog    Maltese `qualifier:"small"`
+}
+
+func TestMissingQualifier(t *testing.T) {
+	_, err := NewRouter(Stray{})
+	if err == nil || !strings.Contains(err.Error(), "qualifier 'big'") {
+		t.Errorf("Expected the missing qualifier to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+)
+
+// Information about a panic recovered while answering a request
+// It is passed to the hook defined with the OnPanic method
+type PanicReport struct {
+	// The value passed to panic
+	Value interface{}
+
+	// The stack trace of the goroutine that panicked
+	Stack []byte
+
+	// The Route that was answering the request
+	// It is nil if the panic occurred before the route resolution
+	Route *RouteInfo
+
+	// The type of the dependency that was being constructed
+	// It is empty if the panic occurred outside a constructor
+	Dependency string
+}
+
+// Define the hook that will receive every recovered panic
+func (ro *route) OnPanic(hook func(*PanicReport)) {
+	ro.panicHook = hook
+}
+
+// Enable or disable the panics recovery, it is enabled by default
+// Disable it in development to let the panics crash with the full stack trace
+func (ro *route) RecoverPanics(enabled bool) {
+	ro.panicRecovery = enabled
+}
+
+// Answer the request that panicked with the standard error format
+// and report the panic to the hook, if it is defined
+func (ro *route) handlePanic(w http.ResponseWriter, value interface{}, info *RouteInfo, c *context) {
+
+	// This panic is used to abort the handler on purpose
+	if value == http.ErrAbortHandler {
+		panic(value)
+	}
+
+	if ro.panicHook != nil {
+		report := &PanicReport{
+			Value: value,
+			Stack: debug.Stack(),
+			Route: info,
+		}
+		if c != nil && c.constructing != nil {
+			report.Dependency = c.constructing.String()
+		}
+		ro.panicHook(report)
+	}
+
+	writeError(w, errors.New(http.StatusText(http.StatusInternalServerError)), http.StatusInternalServerError)
+}
@@ -0,0 +1,87 @@
+// This package tests the panics recovery
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type Crash struct {
+	Fragile Fragile
+}
+
+type Fragile struct{}
+
+func (f *Fragile) New() *Fragile {
+	panic("Fragile broken")
+}
+
+func (f *Fragile) GET() *Fragile {
+	return f
+}
+
+func TestPanicRecovery(t *testing.T) {
+	rt, err := NewRouter(Crash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report *PanicReport
+	rt.OnPanic(func(r *PanicReport) {
+		report = r
+	})
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/crash/fragile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, received %d", http.StatusInternalServerError, w.Code)
+	}
+
+	var errResp ErrorResp
+	err = json.Unmarshal(w.Body.Bytes(), &errResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errResp.Error == nil {
+		t.Fatal("Error not returned")
+	}
+
+	if report == nil {
+		t.Fatal("Panic not reported to the hook")
+	}
+	if report.Value != "Fragile broken" || len(report.Stack) == 0 ||
+		report.Route.Template != "/crash/fragile" || report.Dependency != "*api.Fragile" {
+		t.Fatalf("Panic reported wrong: %v %s %s", report.Value, report.Route.Template, report.Dependency)
+	}
+}
+
+func TestPanicRecoveryDisabled(t *testing.T) {
+	rt, err := NewRouter(Crash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.RecoverPanics(false)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Panic was recovered")
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/crash/fragile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+}
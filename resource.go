@@ -17,10 +17,39 @@ type resource struct {
 	tag       reflect.StructTag
 	isSlice   bool
 	init      *reflect.Method
+	index     []int // Index of the field in the parent Struct
+
+	// Singleton dependencies shared by the whole tree
+	// Just the root Resource stores them
+	singletons map[interface{}]*singleton
+
+	// True if it was added by an embedded Resource
+	inherited bool
+
+	// Names of the children inherited from more than one embedded Resource
+	clashes map[string]bool
+
+	// Test doubles replacing the dependencies, indexed by the replaced type
+	// Just the root Resource stores them
+	overrides map[reflect.Type]*singleton
+
+	// Providers of the dependencies, indexed by the provided type
+	// Just the root Resource stores them
+	providers map[reflect.Type]*provider
+
+	// The initial state of each element, if it is a collection
+	collection *collection
+
+	// Where the elements are stored, if the slice is backed by a Store
+	// If seed is true the initial elements are inserted in it
+	store Store
+	seed  bool
 }
 
 // Create a new Resource tree based on given Struct, its Struct Field and its Resource parent
-func newResource(value reflect.Value, field reflect.StructField, parent *resource) (*resource, error) {
+// If runInit is false the Init methods are validated, but not called
+// If the loader isn't nil it fills the configured fields before the Init runs
+func newResource(value reflect.Value, field reflect.StructField, parent *resource, runInit bool, ld *loader) (*resource, error) {
 	// Check if the value is valid, valid values are:
 	// struct, *struct, []struct, *[]struct, *[]*struct
 	if !isValidValue(value) {
@@ -42,6 +71,7 @@ func newResource(value reflect.Value, field reflect.StructField, parent *resourc
 		tag:       field.Tag,
 		isSlice:   isSliceType(value.Type()),
 		init:      nil, // Appended above
+		index:     field.Index,
 	}
 
 	// Check for circular dependency !!!
@@ -53,40 +83,59 @@ func newResource(value reflect.Value, field reflect.StructField, parent *resourc
 
 	// Check if this resource has a Init method
 	// If it has, validate it and initialize the resource
-	init, exists := r.value.Type().MethodByName("Init")
+	// The Inits of its embedded Resources are composed if Go didn't promote them
+	init, err := methodOrComposition(r.value.Type(), "Init", isValidInit)
+	if err != nil {
+		return nil, err
+	}
+	exists := init != nil
 	if exists {
-		err := isValidInit(init)
+		err := isValidInit(*init)
 		if err != nil {
 			return nil, err
 		}
-		r.init = &init
+		r.init = init
+	}
 
-		// Running Init method
+	// Load the initial state from the environment and the config files
+	// Resources with missing or invalid values aren't initialized
+	loaded := ld == nil || ld.load(r)
 
-		out := make([]reflect.Value, init.Type.NumOut())
-		// Call the init method passing the initial value of the resource
-		out = init.Func.Call([]reflect.Value{r.value})
-		for _, v := range out {
-			// Test is Init returned an error
-			if v.Type() == errorType && !v.IsNil() {
-				return nil, v.Interface().(error)
-			}
-			if ptrOfType(v.Type()) == r.value.Type() {
-				r.value = ptrOfValue(v)
-			}
+	// Running Init method
+	// Inits that depends on other Resources runs after the whole tree is created
+	// Embedded Resources are initialized by the Resource that embeds them
+	if exists && runInit && loaded && !r.anonymous && !r.initHasDependencies() {
+		err := r.runInit()
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	// If it is slice, scan the Elem of this slice
 	if r.isSlice {
 
-		elemValue := elemOfSliceValue(value)
+		// Slices whose Elem has an ID field keeps the state of each element
+		r.collection = newCollection(r.value)
 
-		elem, err := newResource(elemValue, field, r)
+		r.store, r.seed, err = storeOf(r)
 		if err != nil {
 			return nil, err
 		}
 
+		elemValue := elemOfSliceValue(r.value)
+
+		elem, err := newResource(elemValue, field, r, runInit, ld)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.collection != nil {
+			err = r.collection.index()
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		//r.Elem = elem
 		r.addChild(elem)
 
@@ -103,7 +152,7 @@ func newResource(value reflect.Value, field reflect.StructField, parent *resourc
 		// Check if this field is exported: fieldValue.CanInterface()
 		// and if this field is valid fo create Resources: Structs or Slices of Structs
 		if isValidValue(fieldValue) {
-			child, err := newResource(fieldValue, field, r)
+			child, err := newResource(fieldValue, field, r, runInit, ld)
 			if err != nil {
 				return nil, err
 			}
@@ -114,6 +163,11 @@ func newResource(value reflect.Value, field reflect.StructField, parent *resourc
 		}
 	}
 
+	for name := range r.clashes {
+		return nil, fmt.Errorf("The child '%s' is inherited from more than one embedded Resource of %s, "+
+			"declare it in the embedding Resource to choose one", name, r.value.Type())
+	}
+
 	return r, nil
 }
 
@@ -124,10 +178,11 @@ func (parent *resource) addChild(child *resource) error {
 	//log.Printf("%s Anonymous: %v adding Child %s",
 	//	parent.Value.Type(), parent.Anonymous, child.Value.Type())
 
-	// Just add the child to the first non anonymous parent
+	// Just add the child to the first non anonymous parent,
+	// that inherits the children of the Resources it embeds
 	if parent.anonymous {
-		parent.parent.addChild(child)
-		return nil
+		child.inherited = true
+		return parent.parent.addChild(child)
 	}
 
 	// If this child is Anonymous, its father will extends its behavior
@@ -137,11 +192,28 @@ func (parent *resource) addChild(child *resource) error {
 	}
 
 	// Two children can't have the same name, check it before insert them
-	for _, sibling := range parent.children {
-		if child.name == sibling.name {
-			return fmt.Errorf("Two resources have the same name '%s' \nR1: %s, R2: %s, Parent: %s",
-				child.name, sibling.value.Type(), child.value.Type(), parent.value.Type())
+	// The children declared by the Resource override the inherited ones
+	for i, sibling := range parent.children {
+		if child.name != sibling.name {
+			continue
+		}
+		switch {
+		case sibling.inherited && !child.inherited:
+			parent.children[i] = child
+			delete(parent.clashes, child.name)
+			return nil
+		case child.inherited && !sibling.inherited:
+			return nil
+		case child.inherited && sibling.inherited:
+			// It is an error if the Resource doesn't declare it after all
+			if parent.clashes == nil {
+				parent.clashes = map[string]bool{}
+			}
+			parent.clashes[child.name] = true
+			return nil
 		}
+		return fmt.Errorf("Two resources have the same name '%s' \nR1: %s, R2: %s, Parent: %s",
+			child.name, sibling.value.Type(), child.value.Type(), parent.value.Type())
 	}
 
 	parent.children = append(parent.children, child)
@@ -155,36 +227,65 @@ func (parent *resource) addChild(child *resource) error {
 // if Struct type not contained on the resource tree, create a new empty Value for this Type
 func (r *resource) valueOf(t reflect.Type) (reflect.Value, error) {
 
-	for _, child := range r.children {
-		if child.isType(t) {
-			return child.value, nil
-		}
+	res, err := r.resourceOf(t)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if res != nil {
+		return res.value, nil
+	}
+
+	// If it isn't present in the Resource tree
+	// and this type we are searching isn't an interface
+	// So we will use an empty new value for it!
+	return newEmptyValue(t)
+}
+
+// Return the Resource of the Resource tree that is from this Type,
+// or that satisfies this Interface
+// It searches in this Resource children or in its parents children recursively
+// If it isn't present in the Resource tree, return nil
+func (r *resource) resourceOf(t reflect.Type) (*resource, error) {
+
+	// An Interface implemented by more than one Resource of the same level is ambiguous
+	found := r.implementations(t)
+	if len(found) > 1 && t.Kind() == reflect.Interface {
+		return nil, ambiguousError(t, found)
+	}
+	if len(found) > 0 {
+		return found[0], nil
 	}
 
 	// Go recursively until reaching the root
 	if r.parent != nil {
-		return r.parent.valueOf(t)
+		return r.parent.resourceOf(t)
 	}
 
 	// Testing the root of the Resource Tree
 	ok := r.isType(t)
 	if ok {
-		return r.value, nil
+		return r, nil
 	}
 
 	// At this point we tested all Resources in the tree
 	// If we are searching for an Interface, and noone implements it
 	// so we shall throws an error informing user to satisfy this Interface in the Resource Tree
 	if t.Kind() == reflect.Interface {
-		return reflect.Value{}, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"Not found any Resource that implements the Interface "+
 				"type  %s in the Resource tree %s", t, r)
 	}
 
-	// If it isn't present in the Resource tree
-	// and this type we are searching isn't an interface
-	// So we will use an empty new value for it!
-	return newEmptyValue(t)
+	return nil, nil
+}
+
+// Return the root of the Resource tree
+func (r *resource) root() *resource {
+	if r.parent != nil {
+		return r.parent.root()
+	}
+	return r
 }
 
 // Return true if this Resrouce is from by this Type
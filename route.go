@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // This struct stores a tree of routed methods
@@ -30,6 +31,41 @@ type route struct {
 
 	// True if this is a Route for a set of Resources
 	isSlice bool
+
+	// Middlewares that wraps the mapped methods
+	// of this Route and of its children
+	middlewares []Middleware
+
+	// Maximum duration to answer the requests
+	// for this Route and for its children
+	timeout time.Duration
+
+	// Recover the panics when answering requests
+	// and report them to the hook, if defined
+	panicRecovery bool
+	panicHook     func(*PanicReport)
+
+	// Construct the independent dependencies concurrently
+	// for this Route and for its children
+	concurrent bool
+
+	// Requests being answered and Resources to shut down
+	// Shared by the whole Route tree
+	lifecycle *lifecycle
+
+	// Paths of the health endpoints, empty if not served
+	healthPath string
+	readyPath  string
+
+	// Where the links of this Route and of its children are answered
+	// Children without a style defined inherit it
+	linkStyle        LinkStyle
+	linkStyleDefined bool
+
+	// Which ETags this Route and its children answer
+	// Children without a mode defined inherit it
+	etags        ETagMode
+	etagsDefined bool
 }
 
 // It maps the Resource's mapped methods and creates a new Route tree
@@ -38,11 +74,13 @@ func newRoute(r *resource) (*route, error) {
 	//log.Printf("Building Routes for %s\n", r)
 
 	ro := &route{
-		name:     r.name,
-		value:    r.value,
-		methods:  make(map[string]*method),
-		children: make(map[string]*route),
-		isSlice:  r.isSlice,
+		name:          r.name,
+		value:         r.value,
+		methods:       make(map[string]*method),
+		children:      make(map[string]*route),
+		isSlice:       r.isSlice,
+		middlewares:   []Middleware{},
+		panicRecovery: true,
 	}
 
 	// Maps the Resource's mapped Methods
@@ -59,6 +97,12 @@ func newRoute(r *resource) (*route, error) {
 		return nil, err
 	}
 
+	// Now it is safe to walk the Dependencies graph
+	// and compile the injection plan of each method
+	for _, m := range ro.methods {
+		m.plan = newPlan(m)
+	}
+
 	// Go down to the Resource tree
 	// and create Routes recursivelly for each Resource child
 	for _, child := range r.children {
@@ -91,25 +135,16 @@ func (ro *route) scanRoutesFrom(r *resource) error {
 		return err
 	}
 
-	//
-	// TODO
-	//
-	// This Resource Type already extends all the
-	// extended methods, and don't need to map
-	// this extended resource methods
-
-	// All the resources it Exstends
-	// should be mapped to this Route too
-	/*
-		for _, extend := range r.extends {
-			err := ro.scanRoutesFrom(extend)
-			if err != nil {
-				return err
-			}
-		}
-	*/
+	// Resources backed by a Store have the methods they don't declare generated
+	err = ro.mapsStoreMethods(r)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	// This Resource Type already has the mapped methods
+	// of the Resources it extends, promoted by Go,
+	// but the ones declared by more than one of them aren't promoted
+	return checkEmbeddedConflicts(r)
 }
 
 // Maps the methods from one Resource type and attach it to the Route
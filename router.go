@@ -1,12 +1,14 @@
 package api
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // This is the main interface returned to user
@@ -17,8 +19,21 @@ type Router interface {
 
 	Methods() []Method
 	Children() []Router
+	Child(name string) Router
 	IsSlice() bool
 	String() string
+
+	Use(middlewares ...Middleware)
+	Timeout(d time.Duration)
+	OnPanic(hook func(*PanicReport))
+	RecoverPanics(enabled bool)
+	ConstructConcurrently(enabled bool)
+	Hypermedia(style LinkStyle)
+	ETags(mode ETagMode)
+
+	Shutdown(ctx gocontext.Context) error
+	HealthCheck(path string)
+	ReadinessCheck(path string)
 }
 
 type router struct {
@@ -34,10 +49,78 @@ type Method interface {
 // Creates a new Resource tree based on given Struct
 // Receives the Struct to be mapped in a new Resource Tree,
 // it also receive the Field name and Field tag as optional arguments
+// The configured fields are filled from the environment variables
 func NewRouter(object interface{}, args ...string) (*route, error) {
+	return NewRouterWithConfig(object, nil, args...)
+}
+
+// Creates a new Resource tree like NewRouter,
+// filling the configured fields from the environment variables and the config files
+// All the missing or invalid values are reported at once in a *ConfigError
+// The providers and the test doubles of the config construct the dependencies of their types
+func NewRouterWithConfig(object interface{}, config *Config, args ...string) (*route, error) {
 
 	value := reflect.ValueOf(object)
 
+	ld, err := newLoader(config)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := newOverrides(config)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := newProviders(config)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newResource(value, rootField(value, args), nil, true, ld)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ld.err()
+	if err != nil {
+		return nil, err
+	}
+
+	// The doubles and providers should be known before the dependencies are created
+	r.overrides = overrides
+	r.providers = providers
+
+	deferred, err := r.runDependentInits()
+	if err != nil {
+		return nil, err
+	}
+
+	// The Stores receive the elements already initialized
+	err = r.seedStores()
+	if err != nil {
+		return nil, err
+	}
+
+	ro, err := newRoute(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ro.setLifecycle(newLifecycle(r, deferred))
+
+	err = ro.constructEagerSingletons()
+	if err != nil {
+		return nil, err
+	}
+
+	return ro, nil
+}
+
+// Return the Field of the root of the Resource tree
+// It receives the Field name and Field tag as optional arguments
+func rootField(value reflect.Value, args []string) reflect.StructField {
+
 	name := value.Type().Name()
 	tag := ""
 
@@ -51,18 +134,11 @@ func NewRouter(object interface{}, args ...string) (*route, error) {
 		tag = args[1]
 	}
 
-	field := reflect.StructField{
+	return reflect.StructField{
 		Name:      name,
 		Tag:       reflect.StructTag(tag),
 		Anonymous: false,
 	}
-
-	r, err := newResource(value, field, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return newRoute(r)
 }
 
 ///////////////////////////////////////////////////
@@ -75,12 +151,55 @@ func NewRouter(object interface{}, args ...string) (*route, error) {
 // These are saved here to reduce de size of the route.go file
 //
 
+// Stores everything found while resolving the requested URI
+type match struct {
+	// IDs of the resources present in the URI
+	ids idMap
+
+	// Route template segments walked until now
+	// the IDs are replaced by placeholders
+	path []string
+
+	// Middlewares attached in the Routes walked until now
+	// from the root to the matched Route
+	middlewares []Middleware
+
+	// Timeout of the deepest Route walked that defines one
+	timeout time.Duration
+
+	// True if some Route walked constructs the dependencies concurrently
+	concurrent bool
+
+	// Where the links are answered, defined by the deepest Route walked that defines it
+	linkStyle LinkStyle
+
+	// Which ETags are answered, defined by the deepest Route walked that defines it
+	etags ETagMode
+
+	// The Route of the requested Resource and the segments of its URI, with the IDs
+	route *route
+	href  []string
+}
+
 // Return the the method pointed by the URI and httpMethod
-// Fulfill the IDMap with IDs present in the requested URI
-func (ro *route) method(uri []string, httpMethod string, ids idMap) (*method, error) {
+// Fulfill the match with IDs, template and middlewares present in the requested URI
+func (ro *route) method(uri []string, httpMethod string, mt *match) (*method, error) {
 
 	//log.Println("Route Handling", uri, "in the", ro)
 
+	mt.middlewares = append(mt.middlewares, ro.middlewares...)
+	if ro.timeout > 0 {
+		mt.timeout = ro.timeout
+	}
+	mt.concurrent = mt.concurrent || ro.concurrent
+	if ro.linkStyleDefined {
+		mt.linkStyle = ro.linkStyle
+	}
+	if ro.etagsDefined {
+		mt.etags = ro.etags
+	}
+	mt.route = ro
+
 	// Check if is trying to request some Method of this Route
 	if len(uri) == 0 {
 		m, exist := ro.methods[httpMethod]
@@ -94,27 +213,32 @@ func (ro *route) method(uri []string, httpMethod string, ids idMap) (*method, er
 	if len(uri) == 1 {
 		m, exist := ro.methods[httpMethod+uri[0]]
 		if exist {
+			mt.path = append(mt.path, uri[0])
 			return m, nil
 		}
 	}
 
+	mt.href = append(mt.href, uri[0])
+
 	// If we are in a Slice Route, get its ID and search in the Child Route
 	if ro.isSlice {
 		// Get the only child this route has, the slice Element
 		for _, child := range ro.children {
 			// Add its ID to the Map
-			ids[child.value.Type()] = reflect.ValueOf(&id{id: uri[0]})
+			mt.ids[child.value.Type()] = reflect.ValueOf(&id{id: uri[0]})
+			mt.path = append(mt.path, ":"+strings.ToLower(elemOfType(child.value.Type()).Name()))
 			// Continue searching in the Route child
-			return child.method(uri[1:], httpMethod, ids)
+			return child.method(uri[1:], httpMethod, mt)
 		}
 		// It should never occurs, because a slice Resource always has a Elem Resource
-		return nil, fmt.Errorf("Route %s is an slice and has no child!")
+		return nil, fmt.Errorf("Route %s is an slice and has no child!", ro)
 	}
 
 	// If we are in an Elem Route, the only possibility is to have a Child with this Name
 	child, exist := ro.children[uri[0]]
 	if exist {
-		return child.method(uri[1:], httpMethod, ids)
+		mt.path = append(mt.path, uri[0])
+		return child.method(uri[1:], httpMethod, mt)
 	}
 
 	return nil, fmt.Errorf("Not exist any Child '%s' or Action '%s' in the %s", uri[0], httpMethod+strings.Title(uri[0]), ro)
@@ -125,6 +249,30 @@ func (ro *route) method(uri []string, httpMethod string, ids idMap) (*method, er
 func (ro *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	//log.Println("### Serving the resource", req.URL.RequestURI())
 
+	// The health endpoints are answered even during the shutdown
+	if ro.serveHealth(w, req) {
+		return
+	}
+
+	// Requests received after the shutdown has started aren't answered
+	if !ro.lifecycle.enter() {
+		writeError(w, errShuttingDown, http.StatusServiceUnavailable)
+		return
+	}
+	defer ro.lifecycle.leave()
+
+	// Filled as the request goes on, used to report panics
+	var info *RouteInfo
+	var c *context
+
+	if ro.panicRecovery {
+		defer func() {
+			if value := recover(); value != nil {
+				ro.handlePanic(w, value, info, c)
+			}
+		}()
+	}
+
 	// Get the resource identfiers from the URL
 	// Remember to descart the query string: ?q=sfxt&x=132...
 	// Remember to descart the first empty element of the list, before the first /
@@ -137,11 +285,15 @@ func (ro *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// Store the IDs of the resources in the URI
-	ids := idMap{}
+	mt := &match{
+		ids:  idMap{},
+		path: []string{ro.name},
+		href: []string{ro.name},
+	}
 	httpMethod := strings.ToLower(req.Method)
 
 	// Get the method this URI and HTTP method is pointing to
-	method, err := ro.method(uri[1:], httpMethod, ids)
+	method, err := ro.method(uri[1:], httpMethod, mt)
 	if err != nil {
 		writeError(w, err, http.StatusNotFound)
 		return
@@ -149,19 +301,116 @@ func (ro *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	//log.Printf("Route found: %s = %s ids: %q\n", req.URL.RequestURI(), method, ids)
 
+	// Let the middlewares know which Route matched this request
+	info = &RouteInfo{
+		Template:   "/" + strings.Join(mt.path, "/"),
+		HTTPMethod: req.Method,
+		Method:     method.method.Name,
+		Resource:   method.method.Type.In(0).String(),
+	}
+	req = withRouteInfo(req, info)
+
+	// The request context will be done when the Route timeout is reached
+	if mt.timeout > 0 {
+		ctx, cancel := gocontext.WithTimeout(req.Context(), mt.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	// The mapped method is the last Handler of the middleware chain
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c = newContext(method, w, req, mt.ids)
+		c.concurrent = mt.concurrent
+		c.etags = mt.etags
+		if mt.linkStyle != NoLinks {
+			c.links = mt.route.links("/" + strings.Join(mt.href, "/"))
+			c.linkStyle = mt.linkStyle
+		}
+		method.serve(w, c)
+	})
+
+	chain(handler, mt.middlewares).ServeHTTP(w, req)
+}
+
+// Process the request with this Method and write its output in the ResponseWriter
+func (method *method) serve(w http.ResponseWriter, c *context) {
+
+	// After the response has been written, tear down the dependencies
+	// It is updated as the request goes on, if nothing was set the request panicked
+	outcome := errRequestPanicked
+	defer func() {
+		c.teardown(outcome)
+	}()
+
 	// Process the request with the found Method
-	output := newContext(method, w, req, ids).run()
+	output, err := c.run()
+	if err == nil && method.generated {
+		err = outcomeOf(output)
+	}
+	if err != nil {
+		outcome = err
+		writeError(w, err, errorStatus(err))
+		return
+	}
 
-	// If there is no output to sent back
-	if method.method.Type.NumOut() == 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNoContent)
+	// The links could be changed by the method, so they are written just now
+	var links map[string]Link
+	switch c.linkStyle {
+	case LinksMember:
+		links = c.links.all()
+	case LinksHeader:
+		w.Header().Set("Link", c.links.header())
+	}
+
+	body, err := encodeOutput(method.outName, output, links)
+	if err != nil {
+		outcome = err
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	outcome = outcomeOf(output)
+
+	// The cache policy is answered just for the successful reads
+	if method.cache != nil && outcome == nil && (c.request().Method == "GET" || c.request().Method == "HEAD") {
+		method.cache.write(w)
+	}
+
+	// Conditional requests for responses that didn't change are answered as 304
+	if c.notModified(w, output, body) {
 		return
 	}
 
+	writeBody(w, body, method.status)
+}
+
+// Write the outputs of a mapped method in the ResponseWriter encoded as JSON
+// Each output is identified in the response by its name
+// It returns the outcome of the request, the first error outputed by the method
+// or the error encoding the response
+func writeOutput(w http.ResponseWriter, names []string, output []reflect.Value) error {
+	body, err := encodeOutput(names, output, nil)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return err
+	}
+
+	writeBody(w, body, http.StatusOK)
+	return outcomeOf(output)
+}
+
+// Encode the outputs of a mapped method as JSON, with the links in the _links member
+// It returns nil if there is no output to sent back
+func encodeOutput(names []string, output []reflect.Value, links map[string]Link) ([]byte, error) {
+
+	// If there is no output to sent back
+	if len(output) == 0 {
+		return nil, nil
+	}
+
 	// Trans form the method output into an slice of the values
 	// * Needed to generate a JSON response
-	response := make(map[string]interface{}, method.method.Type.NumOut())
+	response := make(map[string]interface{}, len(output))
 	for i, v := range output {
 		if !v.CanInterface() || v.Kind() == reflect.Ptr && v.IsNil() {
 			continue
@@ -188,19 +437,36 @@ func (ro *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			continue
 		}
 
-		response[method.outName[i]] = v.Interface()
+		response[names[i]] = v.Interface()
+	}
+
+	if len(links) > 0 {
+		response["_links"] = links
 	}
 
 	// Encode the output in JSON
-	var jsonResponse []byte
-	jsonResponse, err = json.MarshalIndent(response, "", "\t")
+	jsonResponse, err := json.MarshalIndent(response, "", "\t")
 	if err != nil {
-		writeError(w, errors.New("Error encoding to Json: "+err.Error()), http.StatusInternalServerError)
-		return
+		return nil, errors.New("Error encoding to Json: " + err.Error())
 	}
 
+	return jsonResponse, nil
+}
+
+// Write the encoded outputs with the Status Code
+// Status 200 and 0 are the same, and a nil body or the status 204 sends no content
+func writeBody(w http.ResponseWriter, body []byte, status int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonResponse)
+
+	if body == nil || status == http.StatusNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if status != 0 && status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	w.Write(body)
 }
 
 // Return all accessible Methods in a specific Route
@@ -225,11 +491,34 @@ func (ro *route) Children() []Router {
 	return children
 }
 
+// Return the child Route with this name, or nil if it doesn't exist
+func (ro *route) Child(name string) Router {
+	child, exist := ro.children[name]
+	if !exist {
+		return nil
+	}
+	return child
+}
+
 // Return true if this Route wraps a list of Resources
 func (ro *route) IsSlice() bool {
 	return ro.isSlice
 }
 
+// Define the maximum duration to answer the requests for this Route and its children
+// When it is reached the request context is cancelled and the client receives a 503
+// A timeout defined in a child Route overrides the one defined in its parents
+func (ro *route) Timeout(d time.Duration) {
+	ro.timeout = d
+}
+
+// Enable the concurrent construction of the dependencies for this Route and its children
+// Independent constructors will run in parallel goroutines, and each one
+// will run just after the dependencies it requires are ready
+func (ro *route) ConstructConcurrently(enabled bool) {
+	ro.concurrent = enabled
+}
+
 // Return a text with the name and the type of a specific Route
 func (ro *route) String() string {
 	return fmt.Sprintf("[%s] %s", ro.name, ro.value.Type())
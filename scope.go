@@ -0,0 +1,259 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// The lifetime of a dependency
+type Scope int
+
+const (
+	// A new instance for each request, shared by the whole request
+	// This is the default scope
+	RequestScope Scope = iota
+
+	// Constructed just once and shared by all requests
+	SingletonScope
+
+	// A new instance for each injection point
+	TransientScope
+)
+
+// Resources can implement this interface to define their scope
+// The 'scope' tag in the Resource field overrides it
+type Scoper interface {
+	Scope() Scope
+}
+
+var scoperInterfaceType = reflect.TypeOf((*Scoper)(nil)).Elem()
+
+func (s Scope) String() string {
+	switch s {
+	case SingletonScope:
+		return "singleton"
+	case TransientScope:
+		return "transient"
+	}
+	return "request"
+}
+
+// Stores the value of a singleton dependency
+// It is constructed by the first request that needs it
+type singleton struct {
+	sync.Mutex
+	built bool
+	value reflect.Value
+
+	// The transients constructed to be injected in the singleton
+	// They live as long as the singleton, so they aren't teared down with the request
+	owned []reflect.Value
+
+	// The Resource of the tree it was constructed from, if it is in the tree
+	resource *resource
+
+	// Where it is registered to be shut down, once constructed
+	lifecycle *lifecycle
+}
+
+// Parse the 'scope' tag of a Resource field
+// Ex: `scope:"singleton"`, `scope:"singleton,eager"` or `scope:"transient"`
+// Eager singletons are constructed when the Router is created
+func parseScope(tag reflect.StructTag) (scope Scope, eager bool, defined bool, err error) {
+	value, defined := tag.Lookup("scope")
+	if !defined {
+		return RequestScope, false, false, nil
+	}
+
+	options := strings.Split(value, ",")
+	switch options[0] {
+	case "request":
+		scope = RequestScope
+	case "singleton":
+		scope = SingletonScope
+	case "transient":
+		scope = TransientScope
+	default:
+		return RequestScope, false, true, fmt.Errorf("Invalid scope '%s' in the tag %s", options[0], tag)
+	}
+
+	for _, option := range options[1:] {
+		if option != "eager" || scope != SingletonScope {
+			return RequestScope, false, true, fmt.Errorf("Invalid scope option '%s' in the tag %s", option, tag)
+		}
+		eager = true
+	}
+
+	return scope, eager, true, nil
+}
+
+// Define the scope of the dependency
+// The tag of the Resource field has priority over the Scoper interface
+// Singletons are stored in the root of the Resource tree,
+// so all methods will share the same instance
+func (d *dependency) setScope(res *resource, r *resource) error {
+
+	if res != nil {
+		scope, eager, defined, err := parseScope(res.tag)
+		if err != nil {
+			return err
+		}
+		if defined {
+			d.scope, d.eager = scope, eager
+		}
+	}
+
+	if d.scope == RequestScope && d.value.Type().Implements(scoperInterfaceType) {
+		d.scope = d.value.Interface().(Scoper).Scope()
+	}
+
+	if d.scope != SingletonScope {
+		return nil
+	}
+
+	// Singletons from the Resource tree are identified by its Resource,
+	// the others by its Type
+	var key interface{} = d.value.Type()
+	if res != nil {
+		key = res
+	}
+
+	root := r.root()
+	if root.singletons == nil {
+		root.singletons = make(map[interface{}]*singleton)
+	}
+
+	s, exist := root.singletons[key]
+	if !exist {
+		s = &singleton{resource: res}
+		root.singletons[key] = s
+	}
+	d.singleton = s
+
+	return nil
+}
+
+// Return the singleton value, constructing it if it wasn't yet
+// If its constructor fails, it will be constructed again in the next request
+// It returns false if its constructor, or the constructor of some of its dependencies, failed
+func (c *context) singletonValue(d *dependency) (reflect.Value, bool) {
+	d.singleton.Lock()
+	defer d.singleton.Unlock()
+
+	if d.singleton.built {
+		return d.singleton.value, true
+	}
+
+	// Its dependencies are constructed in a context of their own,
+	// since they are stored in the slots of the singleton plan
+	sc := &context{
+		method:      c.method,
+		ctx:         c.ctx,
+		idMap:       c.idMap,
+		errors:      []reflect.Value{},
+		constructed: []reflect.Value{},
+		slots:       make([]reflect.Value, d.plan.slots),
+	}
+	defer func() {
+		c.errors = append(c.errors, sc.errors...)
+		if sc.constructing != nil {
+			c.constructing = sc.constructing
+		}
+	}()
+
+	for _, s := range d.plan.steps {
+		sc.runStep(s)
+		if sc.failure != nil {
+			// Its dependencies are teared down with the request
+			c.constructed = append(c.constructed, sc.constructed...)
+			return d.new(), false
+		}
+	}
+
+	s := &step{dependency: d, args: d.plan.args}
+	v, ok := sc.constructWith(d, func(value reflect.Value) []reflect.Value {
+		return sc.stepInputs(s, value)
+	})
+	if !ok {
+		c.constructed = append(c.constructed, sc.constructed...)
+		return v, false
+	}
+
+	d.singleton.value = v
+	d.singleton.built = true
+
+	// The transients constructed for the singleton live as long as it
+	d.singleton.owned = sc.constructed
+
+	if d.singleton.lifecycle != nil {
+		d.singleton.lifecycle.register(d.singleton)
+	}
+
+	return v, true
+}
+
+// Check if the singleton dependency depends just on other singletons
+// A singleton can't depend on request scoped values, it would keep
+// the value from the first request forever
+// Transient dependencies are allowed if they also respect this rule
+func checkSingletonDependency(d *dependency, m *method) error {
+	if d.constructor == nil {
+		return nil
+	}
+
+	for i := 1; i < d.constructor.Type.NumIn(); i++ {
+		t := d.constructor.Type.In(i)
+
+		if isContextType(t) {
+			return fmt.Errorf("Singleton %s can't depend on the request value %s",
+				d.value.Type(), t)
+		}
+
+		dep, exist := m.dependencies.vaueOf(t)
+		if !exist { // It should never occurs!
+			return fmt.Errorf("Danger! No dependency %s found! Something very wrong happened!", t)
+		}
+
+		switch dep.scope {
+		case RequestScope:
+			return fmt.Errorf("Singleton %s can't depend on the request scoped %s",
+				d.value.Type(), dep.value.Type())
+		case TransientScope:
+			err := checkSingletonDependency(dep, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Construct the eager singletons used by the mapped methods of this Route tree
+// It returns the first error outputed by their constructors
+func (ro *route) constructEagerSingletons() error {
+	for _, m := range ro.methods {
+		for _, d := range m.dependencies {
+			if d.scope != SingletonScope || !d.eager {
+				continue
+			}
+
+			c := newStartupContext(m)
+			c.singletonValue(d)
+			if len(c.errors) > 0 {
+				return c.errors[0].Interface().(error)
+			}
+		}
+	}
+
+	for _, child := range ro.children {
+		err := child.constructEagerSingletons()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+// This package tests the dependencies scopes
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type Scoped struct {
+	Pool   Pool `scope:"singleton"`
+	Ticket Ticket
+}
+
+// Counts how many times it was constructed
+type Pool struct {
+	Built int
+}
+
+var poolsBuilt int
+
+func (p *Pool) New() *Pool {
+	poolsBuilt += 1
+	p.Built = poolsBuilt
+	return p
+}
+
+func (p *Pool) GET() *Pool {
+	return p
+}
+
+// It is transient by the Scoper interface
+type Ticket struct {
+	Number int
+}
+
+var ticketsBuilt int
+
+func (t *Ticket) Scope() Scope {
+	return TransientScope
+}
+
+func (t *Ticket) New() *Ticket {
+	ticketsBuilt += 1
+	t.Number = ticketsBuilt
+	return t
+}
+
+func (t *Ticket) GETPair(other *Ticket) bool {
+	return t != other && t.Number != other.Number
+}
+
+// The singleton keeps the transient connection it was constructed with
+type Warehouse struct {
+	Depot Depot `scope:"singleton"`
+}
+
+type Depot struct {
+	Line *Line
+}
+
+func (d *Depot) New(l *Line) *Depot {
+	d.Line = l
+	return d
+}
+
+func (d *Depot) GET() bool {
+	return d.Line.Closed
+}
+
+type Line struct {
+	Closed bool
+}
+
+func (l *Line) Scope() Scope {
+	return TransientScope
+}
+
+func (l *Line) Close() error {
+	l.Closed = true
+	return nil
+}
+
+// The singleton isn't constructed while its dependency fails
+type Registry struct {
+	Index Index `scope:"singleton"`
+}
+
+type Index struct {
+	Source *Source
+}
+
+func (i *Index) New(s *Source) *Index {
+	i.Source = s
+	return i
+}
+
+func (i *Index) GET() int {
+	return i.Source.Try
+}
+
+type Source struct {
+	Try int
+}
+
+var sourceTries int
+
+func (s *Source) Scope() Scope {
+	return TransientScope
+}
+
+func (s *Source) New() (*Source, error) {
+	sourceTries++
+	s.Try = sourceTries
+	if sourceTries == 1 {
+		return s, errors.New("Source unavailable")
+	}
+	return s, nil
+}
+
+type BadSingleton struct {
+	Session Session `scope:"singleton"`
+}
+
+type Session struct{}
+
+func (s *Session) New(req *http.Request) *Session {
+	return s
+}
+
+func (s *Session) GET() *Session {
+	return s
+}
+
+func TestSingletonScope(t *testing.T) {
+	// The counters are global, they start from zero in each run
+	poolsBuilt, ticketsBuilt = 0, 0
+
+	rt, err := NewRouter(Scoped{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/scoped/pool", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rt.ServeHTTP(w, req)
+		errorTest(w, t)
+
+		var resp struct{ Pool Pool }
+		err = json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Pool.Built != 1 || poolsBuilt != 1 {
+			t.Fatal("Singleton constructed more than once")
+		}
+	}
+}
+
+func TestTransientScope(t *testing.T) {
+	poolsBuilt, ticketsBuilt = 0, 0
+
+	rt, err := NewRouter(Scoped{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/scoped/ticket/pair", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.ServeHTTP(w, req)
+	errorTest(w, t)
+
+	var resp struct{ Bool bool }
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Bool {
+		t.Fatal("Transient dependency reused in the same request")
+	}
+}
+
+func TestSingletonOwnsItsTransients(t *testing.T) {
+	rt, err := NewRouter(Warehouse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var resp struct{ Bool bool }
+		decodeBody(t, doRequest(rt, "GET", "/warehouse/depot", ""), &resp)
+		if resp.Bool {
+			t.Fatalf("The transient of the singleton was closed by the request %d", i+1)
+		}
+	}
+}
+
+func TestSingletonDependencyFailing(t *testing.T) {
+	sourceTries = 0
+
+	rt, err := NewRouter(Registry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := doRequest(rt, "GET", "/registry/index", "")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d when the singleton dependency fails, got %d",
+			http.StatusInternalServerError, w.Code)
+	}
+
+	// The singleton is constructed by the next request, and kept
+	for i := 0; i < 2; i++ {
+		var resp struct{ Int int }
+		decodeBody(t, doRequest(rt, "GET", "/registry/index", ""), &resp)
+		if resp.Int != 2 {
+			t.Fatalf("Expected the singleton constructed with the try 2, got %d", resp.Int)
+		}
+	}
+}
+
+func TestSingletonDependingOnRequest(t *testing.T) {
+	_, err := NewRouter(BadSingleton{})
+	if err == nil {
+		t.Fatal("Singleton depending on the request was accepted")
+	}
+}
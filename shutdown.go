@@ -0,0 +1,228 @@
+package api
+
+import (
+	gocontext "context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Resources implementing this interface are shut down
+// when the Router is shut down, receiving its context
+// Resources implementing just io.Closer are closed
+type Shutdowner interface {
+	Shutdown(ctx gocontext.Context) error
+}
+
+var shutdownerInterfaceType = reflect.TypeOf((*Shutdowner)(nil)).Elem()
+
+// Error answered to the requests received after the shutdown has started
+var errShuttingDown = errors.New("The server is shutting down")
+
+// Returned by Shutdown when some Resources failed to shut down
+// Each error is wrapped with the type of the Resource
+type ShutdownError struct {
+	Errors []error
+}
+
+func (e *ShutdownError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "Shutdown failed: " + strings.Join(msgs, ", ")
+}
+
+// Keeps track of the requests being answered and of the Resources to shut down
+// It is shared by all Routes of the tree
+type lifecycle struct {
+	sync.Mutex
+	closing  bool
+	closed   bool
+	inflight sync.WaitGroup
+
+	// Resources of the tree in the order they were initialized
+	resources []*resource
+
+	// Singletons in the order they were constructed
+	singletons []*singleton
+}
+
+// Creates the lifecycle of the Resource tree
+// The Resources without Init, or whose Init doesn't depend on other Resources,
+// were initialized in the order they were created,
+// then the ones in the deferred list, in its order
+func newLifecycle(r *resource, deferred []*resource) *lifecycle {
+	lc := &lifecycle{resources: []*resource{}, singletons: []*singleton{}}
+
+	for _, s := range r.singletons {
+		s.lifecycle = lc
+	}
+
+	isDeferred := map[*resource]bool{}
+	for _, res := range deferred {
+		isDeferred[res] = true
+	}
+
+	var scan func(r *resource)
+	scan = func(r *resource) {
+		if !isDeferred[r] {
+			lc.resources = append(lc.resources, r)
+		}
+		for _, child := range r.children {
+			scan(child)
+		}
+	}
+	scan(r)
+
+	lc.resources = append(lc.resources, deferred...)
+
+	return lc
+}
+
+// Register the singleton just constructed, to be shut down with the Router
+func (lc *lifecycle) register(s *singleton) {
+	lc.Lock()
+	defer lc.Unlock()
+	lc.singletons = append(lc.singletons, s)
+}
+
+// Attach the lifecycle to this Route and to its children
+func (ro *route) setLifecycle(lc *lifecycle) {
+	ro.lifecycle = lc
+	for _, child := range ro.children {
+		child.setLifecycle(lc)
+	}
+}
+
+// Register a new request being answered
+// It returns false if the shutdown has started and the request shouldn't be answered
+func (lc *lifecycle) enter() bool {
+	lc.Lock()
+	defer lc.Unlock()
+
+	if lc.closing {
+		return false
+	}
+	lc.inflight.Add(1)
+	return true
+}
+
+// Register that a request was answered
+func (lc *lifecycle) leave() {
+	lc.inflight.Done()
+}
+
+// Return true if the shutdown has started
+func (lc *lifecycle) isClosing() bool {
+	lc.Lock()
+	defer lc.Unlock()
+	return lc.closing
+}
+
+// Stop accepting new requests, wait for the requests being answered
+// and then shut down the singletons in the reverse order they were constructed,
+// then the Resources of the tree in the reverse order they were initialized
+// The Resources replaced by a constructed singleton aren't shut down twice
+// Resources implementing Shutdown(ctx) are shut down, the ones implementing Close are closed
+// If the context is done before the requests are answered, its error is returned
+// and the Resources aren't shut down, so Shutdown can be called again
+// The Resources are shut down just once, the errors they return are collected in a *ShutdownError
+func (ro *route) Shutdown(ctx gocontext.Context) error {
+	lc := ro.lifecycle
+
+	lc.Lock()
+	lc.closing = true
+	lc.Unlock()
+
+	answered := make(chan struct{})
+	go func() {
+		lc.inflight.Wait()
+		close(answered)
+	}()
+
+	select {
+	case <-answered:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	lc.Lock()
+	defer lc.Unlock()
+
+	if lc.closed {
+		return nil
+	}
+	lc.closed = true
+
+	errs := []error{}
+	replaced := map[*resource]bool{}
+	for i := len(lc.singletons) - 1; i >= 0; i-- {
+		s := lc.singletons[i]
+		errs = append(errs, s.shutdown(ctx)...)
+		if s.resource != nil {
+			replaced[s.resource] = true
+		}
+	}
+
+	for i := len(lc.resources) - 1; i >= 0; i-- {
+		if replaced[lc.resources[i]] {
+			continue
+		}
+		err := lc.resources[i].shutdown(ctx)
+		if err != nil {
+			errs = append(errs, &DependencyError{Type: lc.resources[i].value.Type(), Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ShutdownError{Errors: errs}
+	}
+	return nil
+}
+
+// Shut down or close the Resource value
+// The Elem Resources of slices are just the initial value of its Elems, they aren't closed
+func (r *resource) shutdown(ctx gocontext.Context) error {
+	if r.parent != nil && r.parent.isSlice {
+		return nil
+	}
+
+	return shutdownValue(ctx, r.value)
+}
+
+// Shut down the singleton value, then the transients it owns
+// in the reverse order they were constructed
+func (s *singleton) shutdown(ctx gocontext.Context) []error {
+	errs := []error{}
+	values := []reflect.Value{s.value}
+	for i := len(s.owned) - 1; i >= 0; i-- {
+		values = append(values, s.owned[i])
+	}
+	for _, v := range values {
+		err := shutdownValue(ctx, v)
+		if err != nil {
+			errs = append(errs, &DependencyError{Type: v.Type(), Err: err})
+		}
+	}
+	return errs
+}
+
+// Shut down or close the value
+func shutdownValue(ctx gocontext.Context, v reflect.Value) error {
+	if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+
+	if v.Type().Implements(shutdownerInterfaceType) {
+		return v.Interface().(Shutdowner).Shutdown(ctx)
+	}
+
+	if v.Type().Implements(closerInterfaceType) {
+		return v.Interface().(io.Closer).Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+// This package tests the graceful shutdown of the Router
+package api
+
+import (
+	gocontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var shutdowns []string
+
+type Plant struct {
+	Flusher  Flusher
+	Database Database
+}
+
+type Database struct {
+	Open bool
+}
+
+func (db *Database) Init() *Database {
+	db.Open = true
+	return db
+}
+
+func (db *Database) Close() error {
+	shutdowns = append(shutdowns, "database")
+	return nil
+}
+
+// Flusher is initialized after the Database, so it is shut down before it
+type Flusher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *Flusher) Init(db *Database) *Flusher {
+	f.started = make(chan struct{})
+	f.release = make(chan struct{})
+	return f
+}
+
+func (f *Flusher) Shutdown(ctx gocontext.Context) error {
+	shutdowns = append(shutdowns, "flusher")
+	return errors.New("pending entries")
+}
+
+func (f *Flusher) GET() string {
+	close(f.started)
+	<-f.release
+	return "flushed"
+}
+
+// The singleton constructed by the requests is shut down insted of the tree value,
+// then the transient it owns
+type Hatchery struct {
+	Incubator Incubator `scope:"singleton"`
+}
+
+type Incubator struct {
+	Open   bool
+	heater *Heater
+}
+
+func (i *Incubator) New(h *Heater) *Incubator {
+	i.Open = true
+	i.heater = h
+	return i
+}
+
+func (i *Incubator) GET() bool {
+	return i.Open
+}
+
+func (i *Incubator) Close() error {
+	if !i.Open {
+		shutdowns = append(shutdowns, "closed incubator")
+		return nil
+	}
+	shutdowns = append(shutdowns, "incubator")
+	return nil
+}
+
+type Heater struct{}
+
+func (h *Heater) Scope() Scope {
+	return TransientScope
+}
+
+func (h *Heater) Close() error {
+	shutdowns = append(shutdowns, "heater")
+	return nil
+}
+
+// Signals when the Shutdown starts waiting for the requests in flight,
+// after it stopped accepting new requests
+type waitingContext struct {
+	gocontext.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.once.Do(func() { close(c.waiting) })
+	return c.Context.Done()
+}
+
+func TestShutdown(t *testing.T) {
+	shutdowns = nil
+
+	rt, err := NewRouter(Plant{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flusher := rt.Child("flusher").(*route).value.Interface().(*Flusher)
+
+	// The request in flight when the shutdown starts
+	inflight := httptest.NewRecorder()
+	answered := make(chan struct{})
+	go func() {
+		req, _ := http.NewRequest("GET", "/plant/flusher", nil)
+		rt.ServeHTTP(inflight, req)
+		close(answered)
+	}()
+
+	<-flusher.started
+
+	ctx := &waitingContext{Context: gocontext.Background(), waiting: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		done <- rt.Shutdown(ctx)
+	}()
+
+	// The shutdown waits for the request in flight
+	<-ctx.waiting
+	select {
+	case <-done:
+		t.Fatal("Shutdown didn't wait for the request in flight")
+	default:
+	}
+
+	// New requests aren't answered
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/plant/flusher", nil)
+	rt.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d after the shutdown started, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	close(flusher.release)
+	<-answered
+
+	err = <-done
+	var serr *ShutdownError
+	if !errors.As(err, &serr) || len(serr.Errors) != 1 {
+		t.Fatalf("Expected the Flusher error collected, got %v", err)
+	}
+
+	if strings.Join(shutdowns, " ") != "flusher database" {
+		t.Errorf("Resources weren't shut down in the reverse initialization order: %v", shutdowns)
+	}
+
+	if inflight.Code != http.StatusOK {
+		t.Errorf("The request in flight wasn't answered: %d", inflight.Code)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	shutdowns = nil
+
+	rt, err := NewRouter(Plant{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A request that never ends
+	rt.lifecycle.enter()
+
+	// The context is done before the request ends
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+
+	err = rt.Shutdown(ctx)
+	if err != gocontext.Canceled {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+	if len(shutdowns) > 0 {
+		t.Errorf("Resources shouldn't be shut down while requests are in flight: %v", shutdowns)
+	}
+}
+
+func TestShutdownSingletons(t *testing.T) {
+	shutdowns = nil
+
+	rt, err := NewRouter(Hatchery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := doRequest(rt, "GET", "/hatchery/incubator", "")
+	errorTest(w, t)
+
+	err = rt.Shutdown(gocontext.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(shutdowns, ",") != "incubator,heater" {
+		t.Fatalf("Expected the singleton shut down before its transient, got %v", shutdowns)
+	}
+}
@@ -0,0 +1,440 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//
+// Slices backed by a Store have their CRUD methods generated
+// [GET] /things lists a page of the elements, filtered and sorted by the api.ListParams, and [POST] /things inserts a new one
+// [GET], [PUT] and [DELETE] /things/:id reads, replaces and removes the element with the ID
+// The methods declared by the Resources override the generated ones
+//
+
+// Stores the elements of a slice Resource, indexed by their IDs
+// The elements are received and returned as Ptrs to the Elem of the slice
+// Implementations should be safe for concurrent use
+type Store interface {
+	// Return all the elements, in the order they were inserted
+	List() ([]interface{}, error)
+
+	// Return the element with the ID, or ErrNotFound
+	Get(id string) (interface{}, error)
+
+	// Insert the element and return its ID
+	// If the element has no ID, the Store generates one
+	Insert(v interface{}) (string, error)
+
+	// Replace the element with the ID, or return ErrNotFound
+	Update(id string, v interface{}) error
+
+	// Remove the element with the ID, or return ErrNotFound
+	Delete(id string) error
+}
+
+// Slice Resources can implement this interface to define their Store
+// The 'store' tag in the Resource field creates one of the Stores shipped
+// Ex: `store:"memory"`
+type Storer interface {
+	Store() Store
+}
+
+var storerInterfaceType = reflect.TypeOf((*Storer)(nil)).Elem()
+
+// Returned by the Stores when there is no element with the ID
+var ErrNotFound = errors.New("Not found in the store")
+
+// Returned when inserting an element with an ID already in use
+// It is answered as 409
+type DuplicateIDError struct {
+	ID string
+}
+
+func (e *DuplicateIDError) Error() string {
+	return fmt.Sprintf("The ID %s is already in use", e.ID)
+}
+
+func (e *DuplicateIDError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// Returned when the request body can't be decoded
+// It is answered as 400
+type BadRequestError struct {
+	Err error
+}
+
+func (e *BadRequestError) Error() string {
+	return "Invalid request body: " + e.Err.Error()
+}
+
+func (e *BadRequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BadRequestError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// A Store that keeps the elements in memory
+// The IDs are read from the field tagged with `collection:"id"`,
+// the elements without ID receive a sequential one
+type MemoryStore struct {
+	mu       sync.RWMutex
+	ids      []string
+	elements map[string]interface{}
+	next     int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ids:      []string{},
+		elements: map[string]interface{}{},
+	}
+}
+
+func (s *MemoryStore) List() ([]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]interface{}, len(s.ids))
+	for i, id := range s.ids {
+		list[i] = copyOfElement(s.elements[id])
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) Get(id string) (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, exist := s.elements[id]
+	if !exist {
+		return nil, ErrNotFound
+	}
+	return copyOfElement(v), nil
+}
+
+func (s *MemoryStore) Insert(v interface{}) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value := reflect.ValueOf(v)
+	idField, hasID := collectionIDField(value.Type())
+
+	id := ""
+	if hasID {
+		fv := elemOfValue(value).FieldByIndex(idField)
+		if !fv.IsZero() {
+			id = fmt.Sprint(fv.Interface())
+		}
+	}
+
+	if id == "" {
+		id = s.nextID()
+		if hasID {
+			err := setConfigScalar(elemOfValue(value).FieldByIndex(idField), id)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if _, exist := s.elements[id]; exist {
+		return "", &DuplicateIDError{ID: id}
+	}
+
+	s.ids = append(s.ids, id)
+	s.elements[id] = copyOfElement(v)
+	return id, nil
+}
+
+func (s *MemoryStore) Update(id string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exist := s.elements[id]; !exist {
+		return ErrNotFound
+	}
+	s.elements[id] = copyOfElement(v)
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exist := s.elements[id]; !exist {
+		return ErrNotFound
+	}
+	delete(s.elements, id)
+	for i := range s.ids {
+		if s.ids[i] == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Return the next sequential ID not in use
+func (s *MemoryStore) nextID() string {
+	for {
+		s.next++
+		id := strconv.Itoa(s.next)
+		if _, exist := s.elements[id]; !exist {
+			return id
+		}
+	}
+}
+
+// Return a copy of the element, so the stored one can't be changed by the requests
+func copyOfElement(v interface{}) interface{} {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return v
+	}
+	return ptrOfValue(value).Interface()
+}
+
+// Return the Store of the slice Resource, or nil if it has no one
+func storeOf(r *resource) (store Store, seed bool, err error) {
+	if r.value.Type().Implements(storerInterfaceType) {
+		return r.value.Interface().(Storer).Store(), false, nil
+	}
+
+	name, defined := r.tag.Lookup("store")
+	if !defined {
+		return nil, false, nil
+	}
+	if name == "memory" {
+		return NewMemoryStore(), true, nil
+	}
+
+	return nil, false, fmt.Errorf("The Resource %s has an invalid store '%s', "+
+		"it should be 'memory' or implement api.Storer", r.value.Type(), name)
+}
+
+// Insert the initial elements of the slices in the Stores created for them
+// It should run after the Inits, so the elements are stored initialized
+func (r *resource) seedStores() error {
+	if r.store != nil && r.seed {
+		for _, v := range r.initialElements() {
+			_, err := r.store.Insert(v.Interface())
+			if err != nil {
+				return fmt.Errorf("Can't store the initial elements of %s: %s", r.value.Type(), err)
+			}
+		}
+	}
+
+	for _, child := range r.children {
+		err := child.seedStores()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Return Ptrs to the initial elements of the slice Resource
+func (r *resource) initialElements() []reflect.Value {
+	if r.collection != nil {
+		elements := make([]reflect.Value, r.collection.len())
+		for i := range elements {
+			elements[i] = r.collection.element(i)
+		}
+		return elements
+	}
+
+	slice := elemOfValue(r.value)
+	elements := make([]reflect.Value, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		elements = append(elements, ptrOfValue(slice.Index(i)))
+	}
+	return elements
+}
+
+// Return the value of the element with the ID from the Store
+func (d *dependency) stored(id string) (reflect.Value, error) {
+	v, err := d.store.Get(id)
+	if err != nil {
+		return reflect.Value{}, storeError(d.value.Type(), id, err)
+	}
+	return ptrOfValue(reflect.ValueOf(v)), nil
+}
+
+// Errors of the Store saying there is no element with the ID are answered as 404
+func storeError(t reflect.Type, id string, err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return &ElementNotFoundError{Type: t, ID: id}
+	}
+	return err
+}
+
+// Maps the CRUD methods of the Resources backed by a Store
+// The methods already declared by the Resource aren't generated
+func (ro *route) mapsStoreMethods(r *resource) error {
+	var methods []storeMethod
+	switch {
+	case r.store != nil:
+		methods = sliceStoreMethods(r.value.Type(), r.store)
+	case r.parent != nil && r.parent.store != nil:
+		methods = elemStoreMethods(r.value.Type(), r.parent.store)
+	}
+
+	for _, sm := range methods {
+		if _, exist := ro.methods[strings.ToLower(sm.method.Name)]; exist {
+			continue
+		}
+
+		m, err := newMethod(sm.method, r)
+		if err != nil {
+			return err
+		}
+		m.generated = true
+		m.status = sm.status
+
+		err = ro.addMethod(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A method generated for a Resource backed by a Store
+type storeMethod struct {
+	method reflect.Method
+
+	// Status Code sent when the method succeeds
+	status int
+}
+
+func newStoreMethod(name string, status int, in, out []reflect.Type, fn func([]reflect.Value) []reflect.Value) storeMethod {
+	ft := reflect.FuncOf(in, out, false)
+	return storeMethod{
+		method: reflect.Method{
+			Name: name,
+			Type: ft,
+			Func: reflect.MakeFunc(ft, fn),
+		},
+		status: status,
+	}
+}
+
+// Generate the methods of the slice route
+// t is the Ptr to the slice
+func sliceStoreMethods(t reflect.Type, store Store) []storeMethod {
+	sliceType := t.Elem()
+	elemType := ptrOfType(sliceType.Elem())
+
+	list := newStoreMethod("GET", http.StatusOK,
+		[]reflect.Type{t, listParamsType, responseWriterType},
+		[]reflect.Type{pageType, errorType},
+		func(args []reflect.Value) []reflect.Value {
+			elements, err := store.List()
+			if err != nil {
+				return []reflect.Value{reflect.Zero(pageType), errorOutput(err)}
+			}
+
+			slice := reflect.MakeSlice(sliceType, 0, len(elements))
+			for _, v := range elements {
+				elem := ptrOfValue(reflect.ValueOf(v))
+				if sliceType.Elem().Kind() != reflect.Ptr {
+					elem = elem.Elem()
+				}
+				slice = reflect.Append(slice, elem)
+			}
+
+			// The elements are filtered, sorted and paged by the query string
+			p := args[1].Interface().(ListParams)
+			items, total := p.Apply(slice.Interface())
+			page := p.Page(args[2].Interface().(http.ResponseWriter), items, total)
+			return []reflect.Value{reflect.ValueOf(page), errorNilValue}
+		})
+
+	insert := newStoreMethod("POST", http.StatusCreated,
+		[]reflect.Type{t, requestPtrType},
+		[]reflect.Type{elemType, errorType},
+		func(args []reflect.Value) []reflect.Value {
+			v, err := decodeElement(args[1].Interface().(*http.Request), elemType)
+			if err == nil {
+				_, err = store.Insert(v.Interface())
+			}
+			return []reflect.Value{v, errorOutput(err)}
+		})
+
+	return []storeMethod{list, insert}
+}
+
+// Generate the methods of the element route
+// t is the Ptr to the Elem of the slice,
+// that is found in the Store by the ID in the URI before the method is called
+func elemStoreMethods(t reflect.Type, store Store) []storeMethod {
+
+	get := newStoreMethod("GET", http.StatusOK,
+		[]reflect.Type{t},
+		[]reflect.Type{t},
+		func(args []reflect.Value) []reflect.Value {
+			return []reflect.Value{args[0]}
+		})
+
+	update := newStoreMethod("PUT", http.StatusOK,
+		[]reflect.Type{t, requestPtrType, idInterfaceType},
+		[]reflect.Type{t, errorType},
+		func(args []reflect.Value) []reflect.Value {
+			id := args[2].Interface().(ID).String()
+
+			v, err := decodeElement(args[1].Interface().(*http.Request), t)
+			if err == nil {
+				// The element keeps the ID in the URI
+				if idField, ok := collectionIDField(t); ok {
+					err = setConfigScalar(v.Elem().FieldByIndex(idField), id)
+				}
+			}
+			if err == nil {
+				err = storeError(t, id, store.Update(id, v.Interface()))
+			}
+			return []reflect.Value{v, errorOutput(err)}
+		})
+
+	remove := newStoreMethod("DELETE", http.StatusNoContent,
+		[]reflect.Type{t, idInterfaceType},
+		[]reflect.Type{errorType},
+		func(args []reflect.Value) []reflect.Value {
+			id := args[1].Interface().(ID).String()
+			return []reflect.Value{errorOutput(storeError(t, id, store.Delete(id)))}
+		})
+
+	return []storeMethod{get, update, remove}
+}
+
+// Decode the request body into a new element of the type
+// and check the rules of its 'validate' tags
+func decodeElement(req *http.Request, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t.Elem())
+
+	err := json.NewDecoder(req.Body).Decode(v.Interface())
+	if err != nil {
+		return v, &BadRequestError{Err: err}
+	}
+
+	return v, Validate(v.Interface())
+}
+
+// Return the error as an output Value of type error
+func errorOutput(err error) reflect.Value {
+	if err == nil {
+		return errorNilValue
+	}
+	return reflect.ValueOf(&err).Elem()
+}
@@ -0,0 +1,144 @@
+// This package tests the methods generated for the slices backed by a Store
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type Shop struct {
+	Products Products `store:"memory"`
+	Orders   Orders   `store:"memory"`
+}
+
+type Products []Product
+
+type Product struct {
+	ID    int    `collection:"id"`
+	Name  string `validate:"required"`
+	Price int
+}
+
+type Orders []Order
+
+type Order struct {
+	Product string
+}
+
+// Declared methods override the generated ones
+func (o Orders) GET() string {
+	return "declared orders"
+}
+
+func TestStoreMethods(t *testing.T) {
+	rt, err := NewRouter(Shop{Products: Products{{ID: 7, Name: "Gopher plush"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The initial elements are stored
+	var product struct{ Product Product }
+	w := doRequest(rt, "GET", "/shop/products/7", "")
+	json.Unmarshal(w.Body.Bytes(), &product)
+	if w.Code != http.StatusOK || product.Product.Name != "Gopher plush" {
+		t.Fatalf("Expected the initial element, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Inserted elements receive an ID
+	w = doRequest(rt, "POST", "/shop/products", `{"Name": "Mug", "Price": 10}`)
+	json.Unmarshal(w.Body.Bytes(), &product)
+	if w.Code != http.StatusCreated || product.Product.ID != 1 {
+		t.Fatalf("Expected the element created with the ID 1, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(rt, "PUT", "/shop/products/1", `{"ID": 99, "Name": "Mug", "Price": 12}`)
+	json.Unmarshal(w.Body.Bytes(), &product)
+	if w.Code != http.StatusOK || product.Product.ID != 1 || product.Product.Price != 12 {
+		t.Fatalf("Expected the element updated keeping its ID, got %d %s", w.Code, w.Body.String())
+	}
+
+	var products struct {
+		Page struct {
+			Items Products
+			Total int
+		}
+	}
+	w = doRequest(rt, "GET", "/shop/products", "")
+	json.Unmarshal(w.Body.Bytes(), &products)
+	if products.Page.Total != 2 || products.Page.Items[1].Price != 12 {
+		t.Fatalf("Expected the two elements listed, got %s", w.Body.String())
+	}
+
+	w = doRequest(rt, "DELETE", "/shop/products/7", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected the element deleted, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Missing elements and invalid bodies are answered with their status
+	cases := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/shop/products/7", "", http.StatusNotFound},
+		{"PUT", "/shop/products/7", `{"Name": "Plush"}`, http.StatusNotFound},
+		{"DELETE", "/shop/products/7", "", http.StatusNotFound},
+		{"POST", "/shop/products", `{"Name": `, http.StatusBadRequest},
+		{"POST", "/shop/products", `{"Price": 1}`, http.StatusUnprocessableEntity},
+		{"POST", "/shop/products", `{"ID": 1, "Name": "Mug"}`, http.StatusConflict},
+	}
+	for _, c := range cases {
+		w := doRequest(rt, c.method, c.path, c.body)
+		if w.Code != c.status {
+			t.Errorf("Expected %d for [%s] %s, got %d %s", c.status, c.method, c.path, w.Code, w.Body.String())
+		}
+	}
+
+	// The declared method wins over the generated one
+	var resp StringResp
+	w = doRequest(rt, "GET", "/shop/orders", "")
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.String != "declared orders" {
+		t.Errorf("Expected the declared method, got %s", w.Body.String())
+	}
+
+	// The generated methods are listed by the Route
+	generated := 0
+	for _, m := range rt.Child("products").Methods() {
+		if strings.HasSuffix(m.String(), "(generated)") {
+			generated++
+		}
+	}
+	if generated != 2 {
+		t.Errorf("Expected the generated methods listed, got %v", rt.Child("products").Methods())
+	}
+}
+
+func TestMemoryStoreConcurrency(t *testing.T) {
+	s := NewMemoryStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Insert(&Product{Name: "Mug"})
+		}()
+	}
+	wg.Wait()
+
+	list, _ := s.List()
+	if len(list) != 50 {
+		t.Fatalf("Expected 50 elements, got %d", len(list))
+	}
+
+	// The elements returned are copies
+	p, _ := s.Get("1")
+	p.(*Product).Name = "Changed"
+	p, _ = s.Get("1")
+	if p.(*Product).Name != "Mug" {
+		t.Errorf("Expected the stored element untouched, got %+v", p)
+	}
+}
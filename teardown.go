@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"reflect"
+)
+
+// Dependencies implementing this interface are notified
+// with the error outcome of the request after the response has been written
+// A transaction resource could commit on success and roll back on failure
+type Doner interface {
+	Done(err error)
+}
+
+var (
+	donerInterfaceType  = reflect.TypeOf((*Doner)(nil)).Elem()
+	closerInterfaceType = reflect.TypeOf((*io.Closer)(nil)).Elem()
+)
+
+// Outcome passed to the teardown hooks when the request panicked
+var errRequestPanicked = errors.New("The request panicked")
+
+// Call the teardown hooks of the dependencies constructed for this request
+// in the reverse order they were constructed
+// Doners receive the outcome first, then Closers are closed
+// Singletons are shared by all requests and aren't teared down
+func (c *context) teardown(outcome error) {
+	for i := len(c.constructed) - 1; i >= 0; i-- {
+		v := c.constructed[i]
+
+		if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+			continue
+		}
+
+		if v.Type().Implements(donerInterfaceType) {
+			v.Interface().(Doner).Done(outcome)
+		}
+
+		if v.Type().Implements(closerInterfaceType) {
+			err := v.Interface().(io.Closer).Close()
+			if err != nil {
+				log.Printf("Error closing the dependency %s: %s", v.Type(), err)
+			}
+		}
+	}
+}
+
+// Store the value constructed for this request, to be teared down
+func (c *context) track(d *dependency, v reflect.Value) {
+	c.constructed = append(c.constructed, d.injected(v))
+}
+
+// Return the first error outputed by the mapped method
+// It could be an error or the first one of an []error
+func outcomeOf(output []reflect.Value) error {
+	for _, v := range output {
+		if v.Type() == errorType && !v.IsNil() {
+			return v.Interface().(error)
+		}
+		if v.Type() == errorSliceType {
+			for i := 0; i < v.Len(); i++ {
+				if err, ok := v.Index(i).Interface().(error); ok && err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
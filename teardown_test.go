@@ -0,0 +1,72 @@
+// This package tests the teardown hooks called after the request
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var teardowns []string
+
+type Bank struct {
+	Tx Tx
+}
+
+type Conn struct{}
+
+func (c *Conn) Close() error {
+	teardowns = append(teardowns, "close")
+	return nil
+}
+
+type Tx struct{}
+
+func (tx *Tx) New(c *Conn) *Tx {
+	return tx
+}
+
+func (tx *Tx) Done(err error) {
+	if err != nil {
+		teardowns = append(teardowns, "rollback")
+		return
+	}
+	teardowns = append(teardowns, "commit")
+}
+
+func (tx *Tx) POST() error {
+	return nil
+}
+
+func (tx *Tx) POSTFail() error {
+	return errors.New("Transfer failed")
+}
+
+func TestTeardown(t *testing.T) {
+	rt, err := NewRouter(Bank{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"/bank/tx":      "commit close",
+		"/bank/tx/fail": "rollback close",
+	}
+
+	for uri, expected := range cases {
+		teardowns = nil
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rt.ServeHTTP(w, req)
+
+		if len(teardowns) != 2 || teardowns[0]+" "+teardowns[1] != expected {
+			t.Fatalf("Teardown of %s should be %s, it was %q", uri, expected, teardowns)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A validation rule not satisfied by a field
+type Violation struct {
+	// Path of the field from the validated value, like Address.Street or Items[2].Name
+	Field string `json:"field"`
+
+	// The rule as written in the tag, like required or min=1
+	Rule string `json:"rule"`
+}
+
+// Returned when a value doesn't satisfy the rules of its 'validate' tags
+// It lists all the rules violated, and is answered as 422
+type ViolationsError struct {
+	Violations []Violation
+}
+
+func (e *ViolationsError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = fmt.Sprintf("%s (%s)", v.Field, v.Rule)
+	}
+	return "Validation failed on " + strings.Join(parts, ", ")
+}
+
+func (e *ViolationsError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Validate the fields of the struct, or of the structs in the slice,
+// against the rules of their 'validate' tags
+// Ex: `validate:"required,min=1,max=100"`, `validate:"email"` or `validate:"oneof=a b"`
+// The rules, but required, are checked just if the field isn't empty
+// It returns a *ViolationsError listing the rules violated
+func Validate(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if !value.IsValid() {
+		return nil
+	}
+
+	vl, err := validatorOf(value.Type())
+	if err != nil {
+		return err
+	}
+	if vl == nil {
+		return nil
+	}
+
+	return vl.validate(value)
+}
+
+// Validators already compiled, indexed by type
+var validators sync.Map
+
+// Return the validator of the type, or nil if it has no rules
+func validatorOf(t reflect.Type) (*validator, error) {
+	if vl, exist := validators.Load(t); exist {
+		return vl.(*validator), nil
+	}
+
+	vl, err := newValidator(t, map[reflect.Type]*validator{})
+	if err != nil {
+		return nil, err
+	}
+	if vl != nil && !vl.hasRules {
+		vl = nil
+	}
+
+	validators.Store(t, vl)
+	return vl, nil
+}
+
+// The compiled rules of a struct, or of the structs in a slice
+type validator struct {
+	fields []fieldValidator
+
+	// For slices, the validator of its elements
+	elem *validator
+
+	// True if some rule is found in this type or in its fields
+	hasRules bool
+
+	// True if it refers to a type that was being compiled,
+	// so its rules could be found just after it was compiled
+	cyclic bool
+}
+
+type fieldValidator struct {
+	index int
+	name  string
+	rules []rule
+
+	// True if the rules aren't checked for empty values, by the 'omitempty' option
+	omitEmpty bool
+
+	// For structs or slices of structs, the validator of its fields
+	nested *validator
+}
+
+type rule struct {
+	name  string
+	param string
+
+	// Parsed param of the min and max rules
+	limit float64
+
+	// Options of the oneof rule
+	options []string
+}
+
+// Compile the rules of the type
+// It returns nil for types that can't have fields to validate
+// Types being compiled are stored in the building map,
+// so types that refers to themselves can be compiled
+func newValidator(t reflect.Type, building map[reflect.Type]*validator) (*validator, error) {
+	t = elemOfType(t)
+
+	if vl, exist := building[t]; exist {
+		return vl, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		elem, err := newValidator(t.Elem(), building)
+		if err != nil || elem == nil {
+			return nil, err
+		}
+		return &validator{
+			elem:     elem,
+			hasRules: elem.hasRules,
+			cyclic:   elem.cyclic || isBuilding(elem, building),
+		}, nil
+	case reflect.Struct:
+	default:
+		return nil, nil
+	}
+
+	vl := &validator{}
+	building[t] = vl
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // Unexported
+			continue
+		}
+
+		rules, omitEmpty, err := parseRules(field)
+		if err != nil {
+			return nil, err
+		}
+
+		nested, err := newValidator(field.Type, building)
+		if err != nil {
+			return nil, err
+		}
+
+		if nested != nil {
+			cyclic := nested.cyclic || isBuilding(nested, building)
+			vl.cyclic = vl.cyclic || cyclic
+
+			// Fields without rules don't need to be walked
+			if !nested.hasRules && !cyclic {
+				nested = nil
+			}
+		}
+
+		if len(rules) == 0 && nested == nil {
+			continue
+		}
+
+		vl.fields = append(vl.fields, fieldValidator{
+			index:     i,
+			name:      field.Name,
+			rules:     rules,
+			omitEmpty: omitEmpty,
+			nested:    nested,
+		})
+		vl.hasRules = vl.hasRules || len(rules) > 0 || nested.hasRules
+	}
+
+	delete(building, t)
+
+	return vl, nil
+}
+
+// Return true if the validator is still being compiled
+func isBuilding(vl *validator, building map[reflect.Type]*validator) bool {
+	for _, b := range building {
+		if b == vl {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse the rules of the 'validate' tag of the field
+// and return true if it has the 'omitempty' option
+func parseRules(field reflect.StructField) ([]rule, bool, error) {
+	tag, exist := field.Tag.Lookup("validate")
+	if !exist || tag == "" {
+		return nil, false, nil
+	}
+
+	t := elemOfType(field.Type)
+	rules := []rule{}
+	omitEmpty := false
+
+	for _, r := range strings.Split(tag, ",") {
+		if r == "omitempty" {
+			omitEmpty = true
+			continue
+		}
+
+		parts := strings.SplitN(r, "=", 2)
+		ru := rule{name: parts[0]}
+		if len(parts) > 1 {
+			ru.param = parts[1]
+		}
+
+		switch ru.name {
+		case "required":
+		case "min", "max":
+			limit, err := strconv.ParseFloat(ru.param, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("Invalid rule '%s' for the field %s: %s", r, field.Name, err)
+			}
+			if !isNumberKind(t.Kind()) && !hasLength(t.Kind()) {
+				return nil, false, fmt.Errorf("Invalid rule '%s' for the field %s of type %s", r, field.Name, field.Type)
+			}
+			ru.limit = limit
+		case "email":
+			if t.Kind() != reflect.String {
+				return nil, false, fmt.Errorf("Invalid rule '%s' for the field %s of type %s", r, field.Name, field.Type)
+			}
+		case "oneof":
+			ru.options = strings.Fields(ru.param)
+			if len(ru.options) == 0 {
+				return nil, false, fmt.Errorf("Invalid rule '%s' for the field %s, it has no options", r, field.Name)
+			}
+			if t.Kind() != reflect.String && !isNumberKind(t.Kind()) {
+				return nil, false, fmt.Errorf("Invalid rule '%s' for the field %s of type %s", r, field.Name, field.Type)
+			}
+		default:
+			return nil, false, fmt.Errorf("Unknown validation rule '%s' for the field %s", r, field.Name)
+		}
+
+		rules = append(rules, ru)
+	}
+
+	return rules, omitEmpty, nil
+}
+
+// Validate the value, returning a *ViolationsError with all rules violated
+func (vl *validator) validate(v reflect.Value) error {
+	violations := vl.check(v, "", nil)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ViolationsError{Violations: violations}
+}
+
+// Append the rules violated by the value to the list
+// The path is the path of the value from the validated one
+func (vl *validator) check(v reflect.Value, path string, violations []Violation) []Violation {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return violations
+		}
+		v = v.Elem()
+	}
+
+	if vl.elem != nil {
+		for i := 0; i < v.Len(); i++ {
+			violations = vl.elem.check(v.Index(i), fmt.Sprintf("%s[%d]", path, i), violations)
+		}
+		return violations
+	}
+
+	for _, f := range vl.fields {
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+
+		field := v.Field(f.index)
+
+		// The 'omitempty' option skips the rules of empty values
+		if !f.omitEmpty || !isEmptyValue(field) {
+			for _, r := range f.rules {
+				if !r.satisfiedBy(field) {
+					violations = append(violations, Violation{Field: fieldPath, Rule: r.String()})
+				}
+			}
+		}
+
+		if f.nested != nil {
+			violations = f.nested.check(field, fieldPath, violations)
+		}
+	}
+
+	return violations
+}
+
+// Return true if the value is a nil pointer or the zero value of its type
+func isEmptyValue(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.IsZero()
+}
+
+// Return true if the value satisfies the rule
+// Nil pointers satisfy all rules but required,
+// and empty strings, slices and maps satisfy the length rules
+// Zero numbers are checked by its value
+func (r *rule) satisfiedBy(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return r.name != "required"
+		}
+		v = v.Elem()
+	}
+
+	if r.name == "required" {
+		return !v.IsZero()
+	}
+
+	if v.IsZero() && hasLength(v.Kind()) && (r.name == "min" || r.name == "max") {
+		return true
+	}
+
+	switch r.name {
+	case "min":
+		return sizeOf(v) >= r.limit
+	case "max":
+		return sizeOf(v) <= r.limit
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		return err == nil && addr.Address == v.String()
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range r.options {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func (r *rule) String() string {
+	if r.param == "" {
+		return r.name
+	}
+	return r.name + "=" + r.param
+}
+
+// Return the number itself, or the length of strings, slices and maps
+func sizeOf(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String()))
+	}
+	return float64(v.Len())
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+func hasLength(k reflect.Kind) bool {
+	return k == reflect.String || k == reflect.Slice || k == reflect.Map || k == reflect.Array
+}
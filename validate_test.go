@@ -0,0 +1,205 @@
+// This package tests the validation of the constructed resources
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type Registration struct {
+	Applicant Applicant
+	Referrer  Referrer
+}
+
+type Applicant struct {
+	Name    string `validate:"required,max=10"`
+	Email   string `validate:"omitempty,email"`
+	Age     int    `validate:"min=18,max=130"`
+	Plan    string `validate:"oneof=free pro"`
+	Address Address
+	Tags    []Tag
+}
+
+type Address struct {
+	Street string `validate:"required"`
+}
+
+type Tag struct {
+	Name string `validate:"min=2"`
+}
+
+// Binds the Applicant from the query string
+func (a *Applicant) New(req *http.Request) *Applicant {
+	q := req.URL.Query()
+	a.Name = q.Get("name")
+	a.Email = q.Get("email")
+	a.Age, _ = strconv.Atoi(q.Get("age"))
+	a.Plan = q.Get("plan")
+	a.Address.Street = q.Get("street")
+	for _, tag := range strings.Split(q.Get("tags"), ",") {
+		if tag != "" {
+			a.Tags = append(a.Tags, Tag{Name: tag})
+		}
+	}
+	return a
+}
+
+func (a *Applicant) POST() *Applicant {
+	return a
+}
+
+func (a *Applicant) POSTChecked(errs []error) string {
+	var verr *ViolationsError
+	if len(errs) != 1 || !errors.As(errs[0], &verr) {
+		return "no validation error"
+	}
+	return strconv.Itoa(len(verr.Violations))
+}
+
+// It has no constructor, the value in the Resource tree is validated
+type Referrer struct {
+	Code string `validate:"required"`
+}
+
+func (r *Referrer) GET() *Referrer {
+	return r
+}
+
+type violationsResp struct {
+	Error      string
+	Violations []Violation
+}
+
+func TestValidation(t *testing.T) {
+	rt, err := NewRouter(Registration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := "name=Gopher&email=gopher@golang.org&age=20&plan=pro&street=Main&tags=go,api"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/registration/applicant?"+valid, nil)
+	rt.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Valid Applicant answered with %d: %s", w.Code, w.Body)
+	}
+
+	invalid := "name=Gopher+The+Great&email=gopher&age=12&plan=gold&tags=go,a"
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/registration/applicant?"+invalid, nil)
+	rt.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Invalid Applicant answered with %d: %s", w.Code, w.Body)
+	}
+
+	var resp violationsResp
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Violation{
+		{"Name", "max=10"},
+		{"Email", "email"},
+		{"Age", "min=18"},
+		{"Plan", "oneof=free pro"},
+		{"Address.Street", "required"},
+		{"Tags[1].Name", "min=2"},
+	}
+	if !reflect.DeepEqual(resp.Violations, expected) {
+		t.Errorf("Expected the violations %v, got %v", expected, resp.Violations)
+	}
+
+	// Methods that accept the errors receive the validation error
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/registration/applicant/checked?"+invalid, nil)
+	rt.ServeHTTP(w, req)
+
+	var str StringResp
+	err = json.Unmarshal(w.Body.Bytes(), &str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || str.String != "6" {
+		t.Errorf("Expected the validation error injected, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestValidationOfZeroValues(t *testing.T) {
+	rt, err := NewRouter(Registration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The zero Age and the empty Plan are checked, the empty Email is omitted
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/registration/applicant?name=Gopher&street=Main", nil)
+	rt.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Applicant with zero values answered with %d: %s", w.Code, w.Body)
+	}
+
+	var resp violationsResp
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Violation{
+		{"Age", "min=18"},
+		{"Plan", "oneof=free pro"},
+	}
+	if !reflect.DeepEqual(resp.Violations, expected) {
+		t.Errorf("Expected the violations %v, got %v", expected, resp.Violations)
+	}
+}
+
+func TestValidationWithoutConstructor(t *testing.T) {
+	cases := []struct {
+		tree Registration
+		code int
+	}{
+		{Registration{Referrer: Referrer{Code: "gopher"}}, http.StatusOK},
+		{Registration{}, http.StatusUnprocessableEntity},
+	}
+
+	for _, c := range cases {
+		rt, err := NewRouter(c.tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/registration/referrer", nil)
+		rt.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("Expected %d for the Referrer %+v, got %d: %s", c.code, c.tree.Referrer, w.Code, w.Body)
+		}
+	}
+}
+
+func TestInvalidValidationRule(t *testing.T) {
+	type Rule struct {
+		Age int `validate:"email"`
+	}
+
+	if err := Validate(Rule{}); err == nil {
+		t.Error("Expected an error for the email rule in an int field")
+	}
+
+	type Unknown struct {
+		Name string `validate:"uppercase"`
+	}
+
+	if err := Validate(&Unknown{}); err == nil {
+		t.Error("Expected an error for the unknown rule")
+	}
+}